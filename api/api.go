@@ -9,6 +9,7 @@ import (
 	"github.com/lionpuro/neverexpire/hosts"
 	"github.com/lionpuro/neverexpire/keys"
 	"github.com/lionpuro/neverexpire/logging"
+	"github.com/lionpuro/neverexpire/notifications"
 	"github.com/lionpuro/neverexpire/users"
 )
 
@@ -19,12 +20,13 @@ type API struct {
 }
 
 type services struct {
-	users *users.Service
-	hosts *hosts.Service
-	keys  *keys.Service
+	users         *users.Service
+	hosts         *hosts.Service
+	keys          *keys.Service
+	notifications *notifications.Service
 }
 
-func New(mux *http.ServeMux, logger logging.Logger, u *users.Service, h *hosts.Service, k *keys.Service) *API {
+func New(mux *http.ServeMux, logger logging.Logger, u *users.Service, h *hosts.Service, k *keys.Service, n *notifications.Service) *API {
 	conf := huma.DefaultConfig("neverexpire.lionpuro.com", "1.0.0")
 	conf.DocsPath = ""
 	conf.Components.SecuritySchemes = map[string]*huma.SecurityScheme{
@@ -37,9 +39,10 @@ func New(mux *http.ServeMux, logger logging.Logger, u *users.Service, h *hosts.S
 	mux.HandleFunc("/docs/api", docsHandler(logger))
 
 	services := services{
-		users: u,
-		hosts: h,
-		keys:  k,
+		users:         u,
+		hosts:         h,
+		keys:          k,
+		notifications: n,
 	}
 
 	a := &API{
@@ -71,6 +74,141 @@ func (a *API) Register() {
 		Security:    security,
 		Tags:        []string{"Hosts"},
 	}, a.GetHost)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "get-host-history",
+		Method:      http.MethodGet,
+		Path:        "/hosts/{name}/history",
+		Description: "List the certificates seen on a host, most recent first",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Hosts"},
+	}, a.GetHostHistory)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "check-host",
+		Method:      http.MethodPost,
+		Path:        "/hosts/{name}/check",
+		Description: "Check the certificate of a host right away and return the result. A user can check 5 hosts at once and one more every minute after that.",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Hosts"},
+	}, a.CheckHost)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "update-host-settings",
+		Method:      http.MethodPut,
+		Path:        "/hosts/{name}/settings",
+		Description: "Update the notification settings of a host",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Hosts"},
+	}, a.UpdateHostSettings)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "snooze-host",
+		Method:      http.MethodPut,
+		Path:        "/hosts/{name}/snooze",
+		Description: "Snooze the notifications about a host until a time, or until its certificate changes",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Hosts"},
+	}, a.SnoozeHost)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "unsnooze-host",
+		Method:      http.MethodDelete,
+		Path:        "/hosts/{name}/snooze",
+		Description: "End the snooze of a host",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Hosts"},
+	}, a.UnsnoozeHost)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "acknowledge-host",
+		Method:      http.MethodPost,
+		Path:        "/hosts/{name}/acknowledge",
+		Description: "Acknowledge the notifications about a host that are due, until its certificate changes or a new notification is due",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Hosts"},
+	}, a.AcknowledgeHost)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "unacknowledge-host",
+		Method:      http.MethodDelete,
+		Path:        "/hosts/{name}/acknowledge",
+		Description: "Remove the acknowledgement of a host",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Hosts"},
+	}, a.UnacknowledgeHost)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "get-hosts-v2",
+		Method:      http.MethodGet,
+		Path:        "/v2/hosts",
+		Description: "List tracked hosts with every detail of their latest check",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Hosts"},
+	}, a.ListHostsV2)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "get-host-v2",
+		Method:      http.MethodGet,
+		Path:        "/v2/hosts/{name}",
+		Description: "Get host by name with every detail of its latest check",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Hosts"},
+	}, a.GetHostV2)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "get-notifications",
+		Method:      http.MethodGet,
+		Path:        "/notifications",
+		Description: "List notifications, most recent first",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Notifications"},
+	}, a.ListNotifications)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "read-notifications",
+		Method:      http.MethodPost,
+		Path:        "/notifications/read",
+		Description: "Mark notifications as read and return the ones that were unread",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Notifications"},
+	}, a.ReadNotifications)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "get-settings",
+		Method:      http.MethodGet,
+		Path:        "/settings",
+		Description: "Get the notification settings and channels",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Settings"},
+	}, a.GetSettings)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "update-settings",
+		Method:      http.MethodPut,
+		Path:        "/settings",
+		Description: "Update the reminder and alert settings",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Settings"},
+	}, a.UpdateSettings)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "create-channel",
+		Method:      http.MethodPost,
+		Path:        "/settings/channels",
+		Description: "Add a webhook notification channel after sending a test notification to it",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Settings"},
+	}, a.CreateChannel)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "update-channel",
+		Method:      http.MethodPut,
+		Path:        "/settings/channels/{id}",
+		Description: "Update the name or the webhook URL of a notification channel",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Settings"},
+	}, a.UpdateChannel)
 	huma.Register(a.huma, huma.Operation{
 		OperationID: "create-host",
 		Method:      http.MethodPost,
@@ -80,6 +218,24 @@ func (a *API) Register() {
 		Security:    security,
 		Tags:        []string{"Hosts"},
 	}, a.CreateHost)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "create-hosts-batch",
+		Method:      http.MethodPost,
+		Path:        "/hosts:batch",
+		Description: "Add up to 100 hosts, with a result for each",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Hosts"},
+	}, a.CreateHostsBatch)
+	huma.Register(a.huma, huma.Operation{
+		OperationID: "delete-hosts-batch",
+		Method:      http.MethodDelete,
+		Path:        "/hosts:batch",
+		Description: "Delete up to 100 hosts, with a result for each",
+		Middlewares: mw,
+		Security:    security,
+		Tags:        []string{"Hosts"},
+	}, a.DeleteHostsBatch)
 	huma.Register(a.huma, huma.Operation{
 		OperationID: "delete-host",
 		Method:      http.MethodDelete,
@@ -103,6 +259,25 @@ func newResponse[T any](data T) *Response[T] {
 	return r
 }
 
+// ListResponse is a page of a list.
+type ListResponse[T any] struct {
+	Body struct {
+		Data       []T     `json:"data"`
+		Total      int     `json:"total" doc:"Number of items matching the filters on every page"`
+		NextCursor *string `json:"next_cursor" doc:"Cursor of the next page, or null on the last page"`
+	}
+}
+
+func newListResponse[T any](data []T, total int, next string) *ListResponse[T] {
+	r := &ListResponse[T]{}
+	r.Body.Data = data
+	r.Body.Total = total
+	if next != "" {
+		r.Body.NextCursor = &next
+	}
+	return r
+}
+
 func (a *API) writeErr(ctx huma.Context, status int, msg string, errs ...error) {
 	if err := huma.WriteErr(a.huma, ctx, status, msg, errs...); err != nil {
 		a.logger.Error("failed to write error", "error", err.Error())
@@ -2,6 +2,9 @@ package api
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,11 +14,56 @@ import (
 )
 
 type Host struct {
-	Hostname  string     `json:"hostname"`
-	Issuer    *string    `json:"issuer"`
-	ExpiresAt *time.Time `json:"expires_at"`
-	CheckedAt time.Time  `json:"checked_at"`
-	Error     *string    `json:"error"`
+	Hostname  string              `json:"hostname"`
+	Port      int                 `json:"port"`
+	Protocol  string              `json:"protocol" enum:"tls,smtp,imap,pop3,xmpp,postgres"`
+	Issuer    *string             `json:"issuer"`
+	ExpiresAt *time.Time          `json:"expires_at"`
+	CheckedAt time.Time           `json:"checked_at"`
+	Error     *string             `json:"error"`
+	Reason    *string             `json:"reason" enum:"unknown_authority,hostname_mismatch,expired,not_yet_valid,incomplete_chain,weak_signature,other" doc:"Reason the certificate failed verification"`
+	Chain     []hosts.Certificate `json:"chain" doc:"Certificates presented by the host, starting from the leaf. expires_at is the earliest expiry in the chain."`
+	Endpoints []Endpoint          `json:"endpoints" doc:"Result of each address the hostname resolves to"`
+	Mismatch  bool                `json:"fingerprint_mismatch" doc:"Set when the endpoints serve different certificates"`
+	Settings  HostSettings        `json:"settings"`
+	// SnoozedUntil and Acknowledgement are only set while they hold.
+	SnoozedUntil    *time.Time       `json:"snoozed_until" doc:"End of the snooze of the host, or null if it isn't snoozed"`
+	Acknowledgement *Acknowledgement `json:"acknowledgement" doc:"Acknowledgement of the notifications about the host, or null if they aren't acknowledged"`
+}
+
+type Acknowledgement struct {
+	AcknowledgedAt time.Time `json:"acknowledged_at"`
+	Note           string    `json:"note"`
+}
+
+type HostSettings struct {
+	ReminderDays []int `json:"reminder_days" nullable:"true" doc:"Days before expiry to send reminders at, overriding the account settings. Null uses the account settings."`
+	Muted        bool  `json:"muted" doc:"Whether notifications for the host are muted"`
+	// Tags is nil when an update leaves the tags out.
+	Tags []string `json:"tags" required:"false" doc:"Tags of the host. The tags are kept if omitted when updating the settings."`
+}
+
+type Endpoint struct {
+	IP          string     `json:"ip"`
+	Status      string     `json:"status" enum:"unknown,offline,invalid,healthy"`
+	Fingerprint *string    `json:"fingerprint"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Error       *string    `json:"error"`
+}
+
+func newEndpoint(e hosts.Endpoint) Endpoint {
+	result := Endpoint{
+		IP:        e.IP,
+		Status:    e.Status.String(),
+		ExpiresAt: e.ExpiresAt,
+	}
+	if e.Fingerprint != "" {
+		result.Fingerprint = &e.Fingerprint
+	}
+	if e.Error != "" {
+		result.Error = &e.Error
+	}
+	return result
 }
 
 func newHost(h hosts.Host) Host {
@@ -26,10 +74,42 @@ func newHost(h hosts.Host) Host {
 	}
 	result := Host{
 		Hostname:  h.Hostname,
+		Port:      h.Port,
+		Protocol:  h.Protocol.String(),
 		Issuer:    &h.Certificate.IssuedBy,
 		ExpiresAt: h.Certificate.ExpiresAt,
 		CheckedAt: h.Certificate.CheckedAt,
 		Error:     errMsg,
+		Chain:     h.Certificate.Chain,
+		Endpoints: make([]Endpoint, len(h.Certificate.Endpoints)),
+		Mismatch:  h.Certificate.FingerprintMismatch,
+		Settings: HostSettings{
+			ReminderDays: h.Settings.ReminderDays(),
+			Muted:        h.Settings.Muted,
+			Tags:         h.Settings.Tags,
+		},
+	}
+	for i, e := range h.Certificate.Endpoints {
+		result.Endpoints[i] = newEndpoint(e)
+	}
+	if h.Snoozed(time.Now()) {
+		result.SnoozedUntil = h.Settings.SnoozedUntil
+	}
+	if h.Acknowledged() {
+		result.Acknowledgement = &Acknowledgement{
+			AcknowledgedAt: *h.Settings.AcknowledgedAt,
+			Note:           h.Settings.AcknowledgementNote,
+		}
+	}
+	if reason := h.Certificate.Reason; reason != hosts.ReasonNone {
+		r := reason.String()
+		result.Reason = &r
+	}
+	if result.Chain == nil {
+		result.Chain = []hosts.Certificate{}
+	}
+	if result.Settings.Tags == nil {
+		result.Settings.Tags = []string{}
 	}
 	if iss := h.Certificate.IssuedBy; iss == "n/a" || iss == "" {
 		result.Issuer = nil
@@ -37,27 +117,70 @@ func newHost(h hosts.Host) Host {
 	return result
 }
 
-type HostsInput struct{}
+type HostsInput struct {
+	Status        []string  `query:"status" enum:"unknown,offline,invalid,healthy" doc:"Comma separated statuses of the latest check to include"`
+	ExpiresBefore time.Time `query:"expires_before" doc:"Only include hosts whose certificate expires before this time"`
+	Issuer        string    `query:"issuer" doc:"Only include hosts whose certificate is issued by this issuer, ignoring case"`
+	Hostname      string    `query:"hostname" doc:"Only include hosts whose hostname contains this text, ignoring case"`
+	Tag           string    `query:"tag" doc:"Only include hosts with this tag"`
+	Sort          string    `query:"sort" default:"status" enum:"status,-status,expires_at,-expires_at,hostname,-hostname,checked_at,-checked_at" doc:"Field to sort by, descending if prefixed with '-'. status sorts failing hosts first and then by expiry."`
+	Cursor        string    `query:"cursor" doc:"next_cursor of the previous page, with the same filters and sort"`
+	Limit         int       `query:"limit" default:"100" minimum:"1" maximum:"1000" doc:"Maximum number of hosts to return"`
+}
 
-func (a *API) ListHosts(ctx context.Context, input *HostsInput) (*Response[[]Host], error) {
+func (in HostsInput) listOptions() hosts.ListOptions {
+	opts := hosts.ListOptions{
+		Issuer:   in.Issuer,
+		Hostname: in.Hostname,
+		Tag:      strings.ToLower(strings.TrimSpace(in.Tag)),
+		Sort:     in.Sort,
+		Cursor:   in.Cursor,
+		Limit:    in.Limit,
+	}
+	for _, name := range in.Status {
+		if s, ok := hosts.NewCertificateStatus(name); ok {
+			opts.Statuses = append(opts.Statuses, s)
+		}
+	}
+	if !in.ExpiresBefore.IsZero() {
+		opts.ExpiresBefore = &in.ExpiresBefore
+	}
+	return opts
+}
+
+// listHosts returns a page of the hosts of the user, or a huma error if it
+// can't be retrieved.
+func (a *API) listHosts(ctx context.Context, userID string, input HostsInput) (hosts.HostList, error) {
+	list, err := a.services.hosts.List(ctx, userID, input.listOptions())
+	if err != nil {
+		if hosts.IsListError(err) {
+			return hosts.HostList{}, huma.Error400BadRequest(err.Error())
+		}
+		a.logger.Error("failed to get hosts", "error", err.Error())
+		return hosts.HostList{}, huma.Error500InternalServerError("failed to retrieve hosts")
+	}
+	return list, nil
+}
+
+func (a *API) ListHosts(ctx context.Context, input *HostsInput) (*ListResponse[Host], error) {
 	key, ok := ctxAPIKey(ctx)
 	if !ok {
 		return nil, huma.Error401Unauthorized("unauthorized")
 	}
-	hsts, err := a.services.hosts.AllByUser(ctx, key.UserID)
+	list, err := a.listHosts(ctx, key.UserID, *input)
 	if err != nil {
-		a.logger.Error("failed to get hosts", "error", err.Error())
-		return nil, huma.Error500InternalServerError("failed to retrieve hosts")
+		return nil, err
 	}
-	var result []Host
-	for _, h := range hsts {
-		result = append(result, newHost(h))
+	result := make([]Host, len(list.Hosts))
+	for i, h := range list.Hosts {
+		result[i] = newHost(h)
 	}
-	return newResponse(result), nil
+	return newListResponse(result, list.Total, list.NextCursor), nil
 }
 
 type HostInput struct {
-	Name string `path:"name"`
+	Name     string `path:"name" doc:"Hostname, optionally followed by a port (e.g. example.com:8443)"`
+	Protocol string `query:"protocol" enum:"tls,smtp,imap,pop3,xmpp,postgres" doc:"Protocol of the host, inferred from the port if omitted"`
 }
 
 func (a *API) GetHost(ctx context.Context, input *HostInput) (*Response[Host], error) {
@@ -65,20 +188,219 @@ func (a *API) GetHost(ctx context.Context, input *HostInput) (*Response[Host], e
 	if !ok {
 		return nil, huma.Error401Unauthorized("unauthorized")
 	}
-	host, err := a.services.hosts.ByName(ctx, input.Name, key.UserID)
+	host, err := a.hostByName(ctx, key.UserID, input.Name, input.Protocol, "failed to retrieve host information")
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(newHost(host)), nil
+}
+
+func (a *API) GetHostHistory(ctx context.Context, input *HostInput) (*Response[[]hosts.HistoryEntry], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	host, err := a.hostByName(ctx, key.UserID, input.Name, input.Protocol, "failed to retrieve certificate history")
+	if err != nil {
+		return nil, err
+	}
+	history, err := a.services.hosts.History(ctx, host.ID, key.UserID)
+	if err != nil {
+		a.logger.Error("failed to get certificate history", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to retrieve certificate history")
+	}
+	if history == nil {
+		history = []hosts.HistoryEntry{}
+	}
+	return newResponse(history), nil
+}
+
+type UpdateHostSettingsInput struct {
+	Name     string `path:"name" doc:"Hostname, optionally followed by a port (e.g. example.com:8443)"`
+	Protocol string `query:"protocol" enum:"tls,smtp,imap,pop3,xmpp,postgres" doc:"Protocol of the host, inferred from the port if omitted"`
+	Body     HostSettings
+}
+
+func (a *API) UpdateHostSettings(ctx context.Context, input *UpdateHostSettingsInput) (*Response[Host], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	host, err := a.hostByName(ctx, key.UserID, input.Name, input.Protocol, "failed to update host settings")
+	if err != nil {
+		return nil, err
+	}
+	settings := hosts.HostSettings{Muted: input.Body.Muted}
+	if tags := input.Body.Tags; tags != nil {
+		if settings.Tags, err = hosts.CheckTags(tags); err != nil {
+			return nil, huma.Error422UnprocessableEntity(err.Error())
+		}
+	}
+	if days := input.Body.ReminderDays; days != nil {
+		thresholds, err := hosts.ReminderThresholds(days)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity(err.Error())
+		}
+		settings.ReminderThresholds = thresholds
+	}
+	if err := a.services.hosts.UpdateSettings(key.UserID, host.ID, settings); err != nil {
+		a.logger.Error("failed to update host settings", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to update host settings")
+	}
+	host.Settings.ReminderThresholds = settings.ReminderThresholds
+	host.Settings.Muted = settings.Muted
+	if settings.Tags != nil {
+		host.Settings.Tags = settings.Tags
+	}
+	return newResponse(newHost(host)), nil
+}
+
+// hostByName returns the host of the user, or a huma error with errMsg if it
+// can't be retrieved.
+func (a *API) hostByName(ctx context.Context, userID, name, protocol, errMsg string) (hosts.Host, error) {
+	target, err := hosts.ParseTarget(name, protocol)
+	if err != nil {
+		return hosts.Host{}, huma.Error400BadRequest("invalid host name")
+	}
+	host, err := a.services.hosts.ByName(ctx, target, userID)
 	if err != nil {
 		if db.IsErrNoRows(err) {
-			return nil, huma.Error404NotFound("host not found")
+			return hosts.Host{}, huma.Error404NotFound("host not found")
 		}
 		a.logger.Error("failed to get host", "error", err.Error())
-		return nil, huma.Error500InternalServerError("failed to retrieve host information")
+		return hosts.Host{}, huma.Error500InternalServerError(errMsg)
+	}
+	return host, nil
+}
+
+type SnoozeHostInput struct {
+	Name     string `path:"name" doc:"Hostname, optionally followed by a port (e.g. example.com:8443)"`
+	Protocol string `query:"protocol" enum:"tls,smtp,imap,pop3,xmpp,postgres" doc:"Protocol of the host, inferred from the port if omitted"`
+	Body     struct {
+		Until time.Time `json:"until" required:"true" doc:"When the snooze ends"`
+	}
+}
+
+func (a *API) SnoozeHost(ctx context.Context, input *SnoozeHostInput) (*Response[Host], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	until := input.Body.Until.UTC()
+	if err := hosts.CheckSnooze(until, time.Now()); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	host, err := a.hostByName(ctx, key.UserID, input.Name, input.Protocol, "failed to snooze host")
+	if err != nil {
+		return nil, err
+	}
+	if err := a.services.hosts.Snooze(key.UserID, host.ID, &until); err != nil {
+		a.logger.Error("failed to snooze host", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to snooze host")
+	}
+	host, err = a.hostByName(ctx, key.UserID, input.Name, input.Protocol, "failed to retrieve snoozed host")
+	if err != nil {
+		return nil, err
 	}
 	return newResponse(newHost(host)), nil
 }
 
+func (a *API) CheckHost(ctx context.Context, input *HostInput) (*Response[Host], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	host, err := a.hostByName(ctx, key.UserID, input.Name, input.Protocol, "failed to check host")
+	if err != nil {
+		return nil, err
+	}
+	host, err = a.services.hosts.Check(key.UserID, host.ID)
+	if err != nil {
+		var rateErr *hosts.RateLimitError
+		if errors.As(err, &rateErr) {
+			return nil, huma.ErrorWithHeaders(
+				huma.Error429TooManyRequests(rateErr.Error()),
+				http.Header{"Retry-After": {strconv.Itoa(rateErr.Seconds())}},
+			)
+		}
+		a.logger.Error("failed to check host", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to check host")
+	}
+	return newResponse(newHost(host)), nil
+}
+
+func (a *API) UnsnoozeHost(ctx context.Context, input *HostInput) (*Response[Host], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	host, err := a.hostByName(ctx, key.UserID, input.Name, input.Protocol, "failed to end snooze")
+	if err != nil {
+		return nil, err
+	}
+	if err := a.services.hosts.Snooze(key.UserID, host.ID, nil); err != nil {
+		a.logger.Error("failed to end snooze", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to end snooze")
+	}
+	host.Settings.SnoozedAt = nil
+	host.Settings.SnoozedUntil = nil
+	return newResponse(newHost(host)), nil
+}
+
+type AcknowledgeHostInput struct {
+	Name     string `path:"name" doc:"Hostname, optionally followed by a port (e.g. example.com:8443)"`
+	Protocol string `query:"protocol" enum:"tls,smtp,imap,pop3,xmpp,postgres" doc:"Protocol of the host, inferred from the port if omitted"`
+	Body     struct {
+		Note string `json:"note,omitempty" maxLength:"500" doc:"Note about the acknowledgement, e.g. a ticket number"`
+	}
+}
+
+func (a *API) AcknowledgeHost(ctx context.Context, input *AcknowledgeHostInput) (*Response[Host], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	note, err := hosts.ParseAcknowledgementNote(input.Body.Note)
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+	host, err := a.hostByName(ctx, key.UserID, input.Name, input.Protocol, "failed to acknowledge host")
+	if err != nil {
+		return nil, err
+	}
+	if err := a.services.hosts.Acknowledge(key.UserID, host.ID, note); err != nil {
+		a.logger.Error("failed to acknowledge host", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to acknowledge host")
+	}
+	host, err = a.hostByName(ctx, key.UserID, input.Name, input.Protocol, "failed to retrieve acknowledged host")
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(newHost(host)), nil
+}
+
+func (a *API) UnacknowledgeHost(ctx context.Context, input *HostInput) (*Response[Host], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	host, err := a.hostByName(ctx, key.UserID, input.Name, input.Protocol, "failed to remove acknowledgement")
+	if err != nil {
+		return nil, err
+	}
+	if err := a.services.hosts.Unacknowledge(key.UserID, host.ID); err != nil {
+		a.logger.Error("failed to remove acknowledgement", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to remove acknowledgement")
+	}
+	host.Settings.AcknowledgedAt = nil
+	return newResponse(newHost(host)), nil
+}
+
 type CreateHostInput struct {
 	Body struct {
-		Name string `json:"name" required:"true"`
+		Name     string `json:"name" required:"true" doc:"Hostname, optionally followed by a port"`
+		Port     int    `json:"port,omitempty" minimum:"1" maximum:"65535" doc:"Port to connect to, overrides any port in name (default: 443, or the default port of the protocol)"`
+		Protocol string `json:"protocol,omitempty" enum:"tls,smtp,imap,pop3,xmpp,postgres" doc:"Protocol used to negotiate TLS, inferred from the port if omitted"`
 	}
 }
 
@@ -88,13 +410,19 @@ func (a *API) CreateHost(ctx context.Context, input *CreateHostInput) (*Response
 		return nil, huma.Error401Unauthorized("unauthorized")
 	}
 	uid := key.UserID
-	name, err := hosts.ParseHostname(input.Body.Name)
+	target, err := hosts.ParseTarget(input.Body.Name, input.Body.Protocol)
 	if err != nil {
 		return nil, huma.Error400BadRequest("bad request")
 	}
-	if err := a.services.hosts.Create(uid, []string{name}); err != nil {
-		if strings.Contains(err.Error(), "already tracking") {
-			host, err := a.services.hosts.ByName(ctx, name, uid)
+	if port := input.Body.Port; port != 0 {
+		target.Port = port
+		if input.Body.Protocol == "" {
+			target.Protocol = hosts.ProtocolForPort(port)
+		}
+	}
+	if err := a.services.hosts.Create(uid, []hosts.Host{target}); err != nil {
+		if errors.Is(err, hosts.ErrAlreadyTracking) {
+			host, err := a.services.hosts.ByName(ctx, target, uid)
 			if err != nil {
 				a.logger.Error("failed to retrieve new host by name", "error", err.Error())
 				return nil, huma.Error500InternalServerError("failed to get created host")
@@ -103,7 +431,7 @@ func (a *API) CreateHost(ctx context.Context, input *CreateHostInput) (*Response
 		}
 		return nil, huma.Error500InternalServerError("failed to create host")
 	}
-	host, err := a.services.hosts.ByName(ctx, name, uid)
+	host, err := a.services.hosts.ByName(ctx, target, uid)
 	if err != nil {
 		a.logger.Error("failed to retrieve new host by name", "error", err.Error())
 		return nil, huma.Error500InternalServerError("failed to retrieve information for created host")
@@ -117,13 +445,9 @@ func (a *API) DeleteHost(ctx context.Context, input *HostInput) (*struct{}, erro
 		return nil, huma.Error401Unauthorized("unauthorized")
 	}
 	uid := key.UserID
-	host, err := a.services.hosts.ByName(ctx, input.Name, uid)
+	host, err := a.hostByName(ctx, uid, input.Name, input.Protocol, "failed to delete host")
 	if err != nil {
-		if db.IsErrNoRows(err) {
-			return nil, huma.Error404NotFound("host not found")
-		}
-		a.logger.Error("failed to get host", "error", err.Error())
-		return nil, huma.Error500InternalServerError("failed to delete host")
+		return nil, err
 	}
 	if err := a.services.hosts.Delete(uid, host.ID); err != nil {
 		a.logger.Error("failed to get host", "error", err.Error())
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+// BatchHostResult is the result of a single host of a batch.
+type BatchHostResult struct {
+	Name   string  `json:"name" doc:"Name of the host as given in the request"`
+	Status string  `json:"status" enum:"created,already_tracking,invalid,unreachable,deleted,not_found,failed"`
+	Error  *string `json:"error" doc:"Why the host is invalid, unreachable or failed"`
+	Host   *Host   `json:"host" doc:"The host, or null if it isn't tracked"`
+}
+
+type BatchHostsInput struct {
+	Body struct {
+		Names    []string `json:"names" minItems:"1" maxItems:"100" doc:"Hostnames, each optionally followed by a port"`
+		Protocol string   `json:"protocol,omitempty" enum:"tls,smtp,imap,pop3,xmpp,postgres" doc:"Protocol of every host, inferred from the port of each if omitted"`
+	}
+}
+
+// parseBatch parses the names of a batch. It returns the hosts that are
+// valid, along with a result for every name in which the invalid ones are
+// already set.
+func parseBatch(names []string, protocol string) ([]hosts.Host, []BatchHostResult) {
+	var targets []hosts.Host
+	results := make([]BatchHostResult, len(names))
+	for i, name := range names {
+		results[i].Name = name
+		target, err := hosts.ParseTarget(name, protocol)
+		if err != nil {
+			msg := err.Error()
+			results[i].Status = string(hosts.BatchInvalid)
+			results[i].Error = &msg
+			continue
+		}
+		targets = append(targets, target)
+	}
+	return targets, results
+}
+
+// batchResults fills in the results of the valid names of a batch.
+func (a *API) batchResults(results []BatchHostResult, batch []hosts.BatchResult) []BatchHostResult {
+	j := 0
+	for i := range results {
+		if results[i].Status != "" {
+			continue
+		}
+		r := batch[j]
+		j++
+		results[i].Status = string(r.Status)
+		switch r.Status {
+		case hosts.BatchFailed:
+			a.logger.Error("failed to process host in batch", "host", r.Host.Address(), "error", r.Err.Error())
+			msg := "something went wrong"
+			results[i].Error = &msg
+		case hosts.BatchInvalid, hosts.BatchUnreachable:
+			msg := r.Err.Error()
+			results[i].Error = &msg
+		case hosts.BatchNotFound:
+		default:
+			host := newHost(r.Host)
+			results[i].Host = &host
+		}
+	}
+	return results
+}
+
+func (a *API) CreateHostsBatch(ctx context.Context, input *BatchHostsInput) (*Response[[]BatchHostResult], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	targets, results := parseBatch(input.Body.Names, input.Body.Protocol)
+	batch := a.services.hosts.CreateBatch(key.UserID, targets)
+	return newResponse(a.batchResults(results, batch)), nil
+}
+
+func (a *API) DeleteHostsBatch(ctx context.Context, input *BatchHostsInput) (*Response[[]BatchHostResult], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	targets, results := parseBatch(input.Body.Names, input.Body.Protocol)
+	batch := a.services.hosts.DeleteBatch(key.UserID, targets)
+	return newResponse(a.batchResults(results, batch)), nil
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lionpuro/neverexpire/hosts"
+	"github.com/lionpuro/neverexpire/logging"
+)
+
+func TestParseBatch(t *testing.T) {
+	targets, results := parseBatch([]string{"example.com", "not a host", "example.com:8443"}, "")
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 valid hosts, got %d", len(targets))
+	}
+	if targets[1].Port != 8443 {
+		t.Errorf("expected port 8443, got %d", targets[1].Port)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	for i, expected := range []string{"", string(hosts.BatchInvalid), ""} {
+		if results[i].Status != expected {
+			t.Errorf("expected status %q for %s, got %q", expected, results[i].Name, results[i].Status)
+		}
+	}
+	if results[1].Error == nil {
+		t.Error("expected an error for the invalid host")
+	}
+
+	batch := []hosts.BatchResult{
+		{Host: targets[0], Status: hosts.BatchCreated},
+		{Host: targets[1], Status: hosts.BatchNotFound},
+	}
+	results = (&API{}).batchResults(results, batch)
+	if results[0].Status != string(hosts.BatchCreated) || results[0].Host == nil {
+		t.Errorf("expected the created host, got %+v", results[0])
+	}
+	if results[2].Status != string(hosts.BatchNotFound) || results[2].Host != nil {
+		t.Errorf("expected the host not to be found, got %+v", results[2])
+	}
+}
+
+func TestBatchRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	New(mux, logging.DefaultLogger(), nil, nil, nil, nil).Register()
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodPost, path: "/api/hosts:batch"},
+		{method: http.MethodDelete, path: "/api/hosts:batch"},
+		{method: http.MethodDelete, path: "/api/hosts/example.com"},
+	}
+	for _, ts := range tests {
+		t.Run(ts.method+" "+ts.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(ts.method, ts.path, nil))
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+			}
+		})
+	}
+}
+
+func TestBatchLimitDocumented(t *testing.T) {
+	mux := http.NewServeMux()
+	a := New(mux, logging.DefaultLogger(), nil, nil, nil, nil)
+	a.Register()
+	schema, ok := a.huma.OpenAPI().Components.Schemas.Map()["BatchHostsInputBody"]
+	if !ok {
+		t.Fatal("expected the batch input to be documented")
+	}
+	if limit := schema.Properties["names"].MaxItems; limit == nil || *limit != hosts.MaxBatch {
+		t.Errorf("expected at most %d names, got %v", hosts.MaxBatch, limit)
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+func TestListHostsSortsDocumented(t *testing.T) {
+	_, api := humatest.New(t)
+	huma.Register(api, huma.Operation{
+		OperationID: "get-hosts",
+		Method:      http.MethodGet,
+		Path:        "/hosts",
+	}, (&API{}).ListHosts)
+	params := api.OpenAPI().Paths["/hosts"].Get.Parameters
+	i := slices.IndexFunc(params, func(p *huma.Param) bool { return p.Name == "sort" })
+	if i == -1 {
+		t.Fatalf("expected the sort parameter, got %v", params)
+	}
+	var documented []string
+	for _, v := range params[i].Schema.Enum {
+		documented = append(documented, v.(string))
+	}
+	if !slices.Equal(documented, hosts.ListSorts) {
+		t.Errorf("expected sorts %v to be documented, got %v", hosts.ListSorts, documented)
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+// HostV2 is the representation of a host in version 2 of the API. Unlike
+// Host it has every detail of the latest check, and can be narrowed down to
+// the fields asked for.
+type HostV2 struct {
+	Hostname        string              `json:"hostname" required:"false"`
+	Port            int                 `json:"port" required:"false"`
+	Protocol        string              `json:"protocol" required:"false" enum:"tls,smtp,imap,pop3,xmpp,postgres"`
+	Status          string              `json:"status" required:"false" enum:"unknown,offline,invalid,healthy" doc:"Status of the latest check"`
+	DNSNames        []string            `json:"dns_names" required:"false" doc:"Subject alternative names of the certificate"`
+	IP              *string             `json:"ip" required:"false" doc:"Address the latest check connected to"`
+	Issuer          *string             `json:"issuer" required:"false"`
+	Fingerprint     *string             `json:"fingerprint" required:"false" doc:"SHA-1 fingerprint of the leaf certificate"`
+	ExpiresAt       *time.Time          `json:"expires_at" required:"false" doc:"Earliest expiry in the chain"`
+	TimeLeftSeconds *int64              `json:"time_left_seconds" required:"false" doc:"Seconds until expires_at, 0 once it has passed and null if it's unknown"`
+	LatencyMs       int                 `json:"latency_ms" required:"false" doc:"Latency of the latest check in milliseconds"`
+	CheckedAt       time.Time           `json:"checked_at" required:"false"`
+	Error           *string             `json:"error" required:"false"`
+	Reason          *string             `json:"reason" required:"false" enum:"unknown_authority,hostname_mismatch,expired,not_yet_valid,incomplete_chain,weak_signature,other" doc:"Reason the certificate failed verification"`
+	Chain           []hosts.Certificate `json:"chain" required:"false" doc:"Certificates presented by the host, starting from the leaf"`
+	Endpoints       []Endpoint          `json:"endpoints" required:"false" doc:"Result of each address the hostname resolves to"`
+	Mismatch        bool                `json:"fingerprint_mismatch" required:"false" doc:"Set when the endpoints serve different certificates"`
+	Settings        HostSettings        `json:"settings" required:"false"`
+	SnoozedUntil    *time.Time          `json:"snoozed_until" required:"false" doc:"End of the snooze of the host, or null if it isn't snoozed"`
+	Acknowledgement *Acknowledgement    `json:"acknowledgement" required:"false" doc:"Acknowledgement of the notifications about the host, or null if they aren't acknowledged"`
+	// fields are the JSON fields included in the response, or every field
+	// if empty.
+	fields []string
+}
+
+func newHostV2(h hosts.Host, fields []string) HostV2 {
+	v1 := newHost(h)
+	result := HostV2{
+		Hostname:        v1.Hostname,
+		Port:            v1.Port,
+		Protocol:        v1.Protocol,
+		Status:          h.Certificate.Status.String(),
+		DNSNames:        splitDNSNames(h.Certificate.DNSNames),
+		Issuer:          v1.Issuer,
+		ExpiresAt:       v1.ExpiresAt,
+		LatencyMs:       h.Certificate.Latency,
+		CheckedAt:       v1.CheckedAt,
+		Error:           v1.Error,
+		Reason:          v1.Reason,
+		Chain:           v1.Chain,
+		Endpoints:       v1.Endpoints,
+		Mismatch:        v1.Mismatch,
+		Settings:        v1.Settings,
+		SnoozedUntil:    v1.SnoozedUntil,
+		Acknowledgement: v1.Acknowledgement,
+		fields:          fields,
+	}
+	if ip := h.Certificate.IP; ip != "" {
+		result.IP = &ip
+	}
+	if sig := h.Certificate.Signature; sig != "" {
+		result.Fingerprint = &sig
+	}
+	if h.Certificate.ExpiresAt != nil {
+		left := int64(h.Certificate.TimeLeft().Seconds())
+		result.TimeLeftSeconds = &left
+	}
+	return result
+}
+
+// splitDNSNames splits the comma separated DNS names of a certificate.
+func splitDNSNames(names string) []string {
+	result := []string{}
+	for name := range strings.SplitSeq(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// TransformSchema documents that the fields of a host depend on the fields
+// asked for, which is why none of them are required.
+func (h HostV2) TransformSchema(r huma.Registry, s *huma.Schema) *huma.Schema {
+	s.Description = "A host. Only the fields listed in the fields parameter are included, or every field if it's omitted."
+	return s
+}
+
+// MarshalJSON encodes the host with only the selected fields.
+func (h HostV2) MarshalJSON() ([]byte, error) {
+	type host HostV2
+	b, err := json.Marshal(host(h))
+	if err != nil || len(h.fields) == 0 {
+		return b, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]json.RawMessage, len(h.fields))
+	for _, f := range h.fields {
+		if v, ok := all[f]; ok {
+			selected[f] = v
+		}
+	}
+	return json.Marshal(selected)
+}
+
+type HostsV2Input struct {
+	HostsInput
+	Fields []string `query:"fields" enum:"hostname,port,protocol,status,dns_names,ip,issuer,fingerprint,expires_at,time_left_seconds,latency_ms,checked_at,error,reason,chain,endpoints,fingerprint_mismatch,settings,snoozed_until,acknowledgement" doc:"Comma separated fields to include in each host. Every field is included if omitted."`
+}
+
+func (a *API) ListHostsV2(ctx context.Context, input *HostsV2Input) (*ListResponse[HostV2], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	list, err := a.listHosts(ctx, key.UserID, input.HostsInput)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]HostV2, len(list.Hosts))
+	for i, h := range list.Hosts {
+		result[i] = newHostV2(h, input.Fields)
+	}
+	return newListResponse(result, list.Total, list.NextCursor), nil
+}
+
+type HostV2Input struct {
+	Name     string   `path:"name" doc:"Hostname, optionally followed by a port (e.g. example.com:8443)"`
+	Protocol string   `query:"protocol" enum:"tls,smtp,imap,pop3,xmpp,postgres" doc:"Protocol of the host, inferred from the port if omitted"`
+	Fields   []string `query:"fields" enum:"hostname,port,protocol,status,dns_names,ip,issuer,fingerprint,expires_at,time_left_seconds,latency_ms,checked_at,error,reason,chain,endpoints,fingerprint_mismatch,settings,snoozed_until,acknowledgement" doc:"Comma separated fields to include. Every field is included if omitted."`
+}
+
+func (a *API) GetHostV2(ctx context.Context, input *HostV2Input) (*Response[HostV2], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	host, err := a.hostByName(ctx, key.UserID, input.Name, input.Protocol, "failed to retrieve host information")
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(newHostV2(host, input.Fields)), nil
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+func TestHostV2Fields(t *testing.T) {
+	expires := time.Now().Add(48 * time.Hour).UTC()
+	h := hosts.Host{
+		Hostname: "example.com",
+		Port:     443,
+		Protocol: hosts.ProtocolTLS,
+		Certificate: hosts.CertificateInfo{
+			DNSNames:  "example.com, www.example.com",
+			IP:        "192.0.2.1:443",
+			IssuedBy:  "R11",
+			Status:    hosts.CertificateStatusHealthy,
+			ExpiresAt: &expires,
+			Latency:   42,
+			Signature: "ab12",
+		},
+	}
+	tests := []struct {
+		name     string
+		fields   []string
+		expected []string
+	}{
+		{
+			name:     "every field",
+			fields:   nil,
+			expected: jsonFields(reflect.TypeFor[HostV2]()),
+		},
+		{
+			name:     "selected fields",
+			fields:   []string{"hostname", "dns_names", "time_left_seconds"},
+			expected: []string{"dns_names", "hostname", "time_left_seconds"},
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			b, err := json.Marshal(newHostV2(h, ts.fields))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var result map[string]any
+			if err := json.Unmarshal(b, &result); err != nil {
+				t.Fatalf("failed to decode host: %v", err)
+			}
+			var keys []string
+			for k := range result {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			expected := slices.Sorted(slices.Values(ts.expected))
+			if !slices.Equal(keys, expected) {
+				t.Errorf("expected fields %v, got %v", expected, keys)
+			}
+			if names, ok := result["dns_names"].([]any); ok && len(names) != 2 {
+				t.Errorf("expected 2 DNS names, got %v", names)
+			}
+			if left, ok := result["time_left_seconds"].(float64); ok && (left <= 47*3600 || left > 48*3600) {
+				t.Errorf("expected about 48 hours left, got %v seconds", left)
+			}
+		})
+	}
+}
+
+// jsonFields returns the names of the JSON fields of a struct.
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && f.IsExported() {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+func TestHostV2FieldsDocumented(t *testing.T) {
+	_, api := humatest.New(t)
+	huma.Register(api, huma.Operation{
+		OperationID: "get-hosts-v2",
+		Method:      http.MethodGet,
+		Path:        "/v2/hosts",
+	}, (&API{}).ListHostsV2)
+	params := api.OpenAPI().Paths["/v2/hosts"].Get.Parameters
+	i := slices.IndexFunc(params, func(p *huma.Param) bool { return p.Name == "fields" })
+	if i == -1 {
+		t.Fatalf("expected the fields parameter, got %v", params)
+	}
+	var documented []string
+	for _, v := range params[i].Schema.Items.Enum {
+		documented = append(documented, v.(string))
+	}
+	if expected := jsonFields(reflect.TypeFor[HostV2]()); !slices.Equal(documented, expected) {
+		t.Errorf("expected fields %v to be documented, got %v", expected, documented)
+	}
+	schema, ok := api.OpenAPI().Components.Schemas.Map()["HostV2"]
+	if !ok {
+		t.Fatal("expected the HostV2 schema")
+	}
+	if len(schema.Required) != 0 {
+		t.Errorf("expected no required fields, got %v", schema.Required)
+	}
+	if !strings.Contains(schema.Description, "fields parameter") {
+		t.Errorf("expected the description to mention the fields parameter, got %q", schema.Description)
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/lionpuro/neverexpire/notifications"
+)
+
+type Notification struct {
+	ID           int        `json:"id"`
+	Type         string     `json:"type" enum:"expiration,renewal,offline,invalid,recovered"`
+	Message      string     `json:"message"`
+	Host         *string    `json:"host" doc:"Name of the host, or null if it's no longer tracked"`
+	Due          time.Time  `json:"due" doc:"When the notification became due"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DeliveredAt  *time.Time `json:"delivered_at" doc:"When the notification was sent to every channel, or null if it hasn't been"`
+	ReadAt       *time.Time `json:"read_at" doc:"When the notification was marked as read, or null if it's unread"`
+	Acknowledged bool       `json:"acknowledged" doc:"Whether the host has been acknowledged since the notification was due"`
+	SnoozedUntil *time.Time `json:"snoozed_until" doc:"End of the snooze of the host, or null if the host isn't snoozed or was snoozed before the notification was due"`
+}
+
+func newNotification(n notifications.AppNotification, hostNames map[int]string) Notification {
+	result := Notification{
+		ID:           n.ID,
+		Type:         n.Type.String(),
+		Message:      n.Body,
+		Due:          n.Due,
+		CreatedAt:    n.CreatedAt,
+		DeliveredAt:  n.DeliveredAt,
+		ReadAt:       n.ReadAt,
+		Acknowledged: n.Acknowledged,
+		SnoozedUntil: n.SnoozedUntil,
+	}
+	if name, ok := hostNames[n.HostID]; ok {
+		result.Host = &name
+	}
+	return result
+}
+
+// notifications returns the notifications of the user, or a huma error with
+// errMsg if they can't be retrieved.
+func (a *API) notifications(ctx context.Context, userID, errMsg string) ([]Notification, error) {
+	notifs, err := a.services.notifications.AllByUser(ctx, userID)
+	if err != nil {
+		a.logger.Error("failed to get notifications", "error", err.Error())
+		return nil, huma.Error500InternalServerError(errMsg)
+	}
+	hsts, err := a.services.hosts.AllByUser(ctx, userID)
+	if err != nil {
+		a.logger.Error("failed to get hosts", "error", err.Error())
+		return nil, huma.Error500InternalServerError(errMsg)
+	}
+	hostNames := make(map[int]string, len(hsts))
+	for _, h := range hsts {
+		hostNames[h.ID] = h.Address()
+	}
+	result := make([]Notification, len(notifs))
+	for i, n := range notifs {
+		result[i] = newNotification(n, hostNames)
+	}
+	return result, nil
+}
+
+type NotificationsInput struct {
+	Unread bool `query:"unread" doc:"Only include unread notifications"`
+}
+
+func (a *API) ListNotifications(ctx context.Context, input *NotificationsInput) (*Response[[]Notification], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	notifs, err := a.notifications(ctx, key.UserID, "failed to retrieve notifications")
+	if err != nil {
+		return nil, err
+	}
+	if input.Unread {
+		notifs = slices.DeleteFunc(notifs, func(n Notification) bool { return n.ReadAt != nil })
+	}
+	return newResponse(notifs), nil
+}
+
+type ReadNotificationsInput struct {
+	// Body is nil when every unread notification is marked as read.
+	Body *struct {
+		IDs []int `json:"ids,omitempty" maxItems:"1000" doc:"IDs of the notifications to mark as read. Every unread notification is marked as read if omitted or empty."`
+	}
+}
+
+func (a *API) ReadNotifications(ctx context.Context, input *ReadNotificationsInput) (*Response[[]Notification], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	notifs, err := a.notifications(ctx, key.UserID, "failed to update notifications")
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	if input.Body != nil {
+		ids = input.Body.IDs
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(notifs, func(n Notification) bool { return n.ID == id }) {
+			return nil, huma.Error404NotFound(fmt.Sprintf("notification %d not found", id))
+		}
+	}
+	now := time.Now().UTC()
+	read := []Notification{}
+	var updates []notifications.NotificationUpdate
+	for _, n := range notifs {
+		if n.ReadAt != nil {
+			continue
+		}
+		if len(ids) > 0 && !slices.Contains(ids, n.ID) {
+			continue
+		}
+		n.ReadAt = &now
+		read = append(read, n)
+		updates = append(updates, notifications.NotificationUpdate{ID: n.ID, ReadAt: &now})
+	}
+	if err := a.services.notifications.Update(key.UserID, updates); err != nil {
+		a.logger.Error("failed to update notifications", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to update notifications")
+	}
+	return newResponse(read), nil
+}
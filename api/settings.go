@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/lionpuro/neverexpire/db"
+	"github.com/lionpuro/neverexpire/notifications"
+	"github.com/lionpuro/neverexpire/users"
+)
+
+type Settings struct {
+	ReminderDays     []int     `json:"reminder_days" doc:"Days before expiry to send reminders at, largest first. 0 reminds when the certificate has expired."`
+	FailureThreshold int       `json:"failure_threshold" doc:"Failed checks in a row before a host is reported offline or invalid"`
+	TimeZone         string    `json:"time_zone" doc:"IANA time zone dates are shown in"`
+	Email            *string   `json:"email" doc:"Verified email address, or null if there is none"`
+	Channels         []Channel `json:"channels" doc:"Channels notifications are sent to"`
+}
+
+type Channel struct {
+	ID       int     `json:"id"`
+	Name     string  `json:"name"`
+	Provider string  `json:"provider"`
+	URL      *string `json:"url" doc:"Webhook URL, or null for email channels"`
+	Failing  bool    `json:"failing" doc:"Set when the latest deliveries to the channel have failed"`
+	// Secret is only set when a generic webhook is created, since it isn't
+	// shown again.
+	Secret *string `json:"secret,omitempty" required:"false" doc:"Secret the payloads of a generic webhook are signed with. Only returned when the channel is created."`
+}
+
+func newChannel(c notifications.Channel) Channel {
+	result := Channel{
+		ID:       c.ID,
+		Name:     c.Name,
+		Provider: c.Provider.String(),
+		Failing:  c.Failing(),
+	}
+	if c.URL != "" {
+		result.URL = &c.URL
+	}
+	return result
+}
+
+func newSettings(s users.Settings, channels []notifications.Channel) Settings {
+	result := Settings{
+		ReminderDays:     make([]int, len(s.ReminderThresholds)),
+		FailureThreshold: s.FailureThreshold,
+		TimeZone:         s.TimeZone,
+		Channels:         make([]Channel, len(channels)),
+	}
+	for i, sec := range s.ReminderThresholds {
+		result.ReminderDays[i] = sec / notifications.ThresholdDay
+	}
+	if s.EmailVerified() {
+		result.Email = &s.Email
+	}
+	for i, c := range channels {
+		result.Channels[i] = newChannel(c)
+	}
+	return result
+}
+
+// settings returns the settings of the user, saving the defaults if the user
+// has none, or a huma error with errMsg if they can't be retrieved.
+func (a *API) settings(ctx context.Context, userID, errMsg string) (Settings, error) {
+	sett, err := a.services.users.Settings(ctx, userID)
+	if db.IsErrNoRows(err) {
+		sett, err = a.services.users.SaveSettings(userID, users.SettingsInput{
+			ReminderThresholds: []int{notifications.Threshold2Weeks},
+		})
+	}
+	if err != nil {
+		a.logger.Error("failed to get settings", "error", err.Error())
+		return Settings{}, huma.Error500InternalServerError(errMsg)
+	}
+	channels, err := a.services.notifications.Channels(ctx, userID)
+	if err != nil {
+		a.logger.Error("failed to get channels", "error", err.Error())
+		return Settings{}, huma.Error500InternalServerError(errMsg)
+	}
+	return newSettings(sett, channels), nil
+}
+
+func (a *API) GetSettings(ctx context.Context, input *struct{}) (*Response[Settings], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	sett, err := a.settings(ctx, key.UserID, "failed to retrieve settings")
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(sett), nil
+}
+
+type UpdateSettingsInput struct {
+	Body struct {
+		ReminderDays     []int `json:"reminder_days,omitempty" nullable:"true" doc:"Days before expiry to send reminders at, each one of 30, 14, 7, 2, 1 or 0. An empty list turns reminders off, and the days are kept if omitted."`
+		FailureThreshold *int  `json:"failure_threshold,omitempty" minimum:"1" doc:"Failed checks in a row before a host is reported offline or invalid, kept if omitted"`
+	}
+}
+
+func (a *API) UpdateSettings(ctx context.Context, input *UpdateSettingsInput) (*Response[Settings], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	update := users.SettingsInput{FailureThreshold: input.Body.FailureThreshold}
+	if days := input.Body.ReminderDays; days != nil {
+		thresholds := make([]int, len(days))
+		for i, d := range days {
+			thresholds[i] = d * notifications.ThresholdDay
+		}
+		thresholds, err := notifications.CheckThresholds(thresholds)
+		if err != nil {
+			return nil, huma.Error422UnprocessableEntity("reminder days must each be one of 30, 14, 7, 2, 1 or 0")
+		}
+		update.ReminderThresholds = thresholds
+	}
+	if _, err := a.services.users.SaveSettings(key.UserID, update); err != nil {
+		a.logger.Error("failed to update settings", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to update settings")
+	}
+	sett, err := a.settings(ctx, key.UserID, "failed to retrieve updated settings")
+	if err != nil {
+		return nil, err
+	}
+	return newResponse(sett), nil
+}
+
+type CreateChannelInput struct {
+	Body struct {
+		Name     string `json:"name,omitempty" maxLength:"64" doc:"Name of the channel, the name of the provider if omitted"`
+		Provider string `json:"provider" enum:"DISCORD,SLACK,TEAMS,MATTERMOST,GOOGLE_CHAT,NTFY,PAGERDUTY,WEBHOOK" doc:"Provider of the webhook. Email channels can only be added in the app."`
+		URL      string `json:"url" doc:"Webhook URL of the channel"`
+	}
+}
+
+func (a *API) CreateChannel(ctx context.Context, input *CreateChannelInput) (*Response[Channel], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	provider, ok := notifications.NewWebhookProvider(input.Body.Provider)
+	if !ok || provider == notifications.EmailProvider {
+		return nil, huma.Error422UnprocessableEntity("invalid webhook provider")
+	}
+	ch := notifications.Channel{
+		UserID:   key.UserID,
+		Name:     strings.TrimSpace(input.Body.Name),
+		Provider: provider,
+		URL:      strings.TrimSpace(input.Body.URL),
+	}
+	if ch.Name == "" {
+		ch.Name = provider.Label()
+	}
+	if !provider.ValidateURL(ch.URL) {
+		return nil, huma.Error422UnprocessableEntity("invalid webhook url")
+	}
+	if provider == notifications.GenericProvider {
+		secret, err := notifications.NewWebhookSecret()
+		if err != nil {
+			a.logger.Error("failed to generate webhook secret", "error", err.Error())
+			return nil, huma.Error500InternalServerError("failed to create channel")
+		}
+		ch.Secret = secret
+	}
+	if err := notifications.SendTestNotification(ch.Provider, ch.URL, ch.Secret); err != nil {
+		a.logger.Error("failed to test notification channel", "error", err.Error())
+		return nil, huma.Error422UnprocessableEntity("error sending test notification")
+	}
+	id, err := a.services.notifications.CreateChannel(ch)
+	if err != nil {
+		a.logger.Error("failed to create channel", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to create channel")
+	}
+	ch.ID = id
+	result := newChannel(ch)
+	if ch.Secret != "" {
+		result.Secret = &ch.Secret
+	}
+	return newResponse(result), nil
+}
+
+type UpdateChannelInput struct {
+	ID   int `path:"id"`
+	Body struct {
+		Name string `json:"name,omitempty" maxLength:"64" doc:"Name of the channel, kept if omitted"`
+		URL  string `json:"url,omitempty" doc:"Webhook URL of the channel, kept if omitted. Email channels don't have one."`
+	}
+}
+
+func (a *API) UpdateChannel(ctx context.Context, input *UpdateChannelInput) (*Response[Channel], error) {
+	key, ok := ctxAPIKey(ctx)
+	if !ok {
+		return nil, huma.Error401Unauthorized("unauthorized")
+	}
+	ch, err := a.services.notifications.Channel(ctx, input.ID, key.UserID)
+	if err != nil {
+		if db.IsErrNoRows(err) {
+			return nil, huma.Error404NotFound("channel not found")
+		}
+		a.logger.Error("failed to get channel", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to update channel")
+	}
+	if name := strings.TrimSpace(input.Body.Name); name != "" {
+		ch.Name = name
+	}
+	if url := strings.TrimSpace(input.Body.URL); url != "" {
+		if ch.Provider == notifications.EmailProvider {
+			return nil, huma.Error422UnprocessableEntity("email channels don't have a URL")
+		}
+		if !ch.Provider.ValidateURL(url) {
+			return nil, huma.Error422UnprocessableEntity("invalid webhook url")
+		}
+		if url != ch.URL {
+			ch.URL = url
+			ch.ConsecutiveFailures = 0
+		}
+	}
+	if err := a.services.notifications.UpdateChannel(ch); err != nil {
+		a.logger.Error("failed to update channel", "error", err.Error())
+		return nil, huma.Error500InternalServerError("failed to update channel")
+	}
+	return newResponse(newChannel(ch)), nil
+}
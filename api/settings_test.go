@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/lionpuro/neverexpire/logging"
+	"github.com/lionpuro/neverexpire/notifications"
+)
+
+func TestNotificationAndSettingsRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	New(mux, logging.DefaultLogger(), nil, nil, nil, nil).Register()
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodGet, path: "/api/notifications"},
+		{method: http.MethodPost, path: "/api/notifications/read"},
+		{method: http.MethodGet, path: "/api/settings"},
+		{method: http.MethodPut, path: "/api/settings"},
+		{method: http.MethodPost, path: "/api/settings/channels"},
+		{method: http.MethodPut, path: "/api/settings/channels/1"},
+	}
+	for _, ts := range tests {
+		t.Run(ts.method+" "+ts.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(ts.method, ts.path, nil))
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+			}
+		})
+	}
+}
+
+func TestReadNotificationsBodyOptional(t *testing.T) {
+	a := New(http.NewServeMux(), logging.DefaultLogger(), nil, nil, nil, nil)
+	a.Register()
+	op := a.huma.OpenAPI().Paths["/notifications/read"].Post
+	if op.RequestBody == nil || op.RequestBody.Required {
+		t.Error("expected the request body to be optional")
+	}
+}
+
+func TestCreateChannelProviders(t *testing.T) {
+	a := New(http.NewServeMux(), logging.DefaultLogger(), nil, nil, nil, nil)
+	a.Register()
+	schema := a.huma.OpenAPI().Components.Schemas.Map()["CreateChannelInputBody"]
+	if schema == nil {
+		t.Fatal("expected the CreateChannelInputBody schema")
+	}
+	var documented []notifications.WebhookProvider
+	for _, v := range schema.Properties["provider"].Enum {
+		documented = append(documented, notifications.WebhookProvider(v.(string)))
+	}
+	if !slices.Equal(documented, notifications.WebhookProviders) {
+		t.Errorf("expected providers %v to be documented, got %v", notifications.WebhookProviders, documented)
+	}
+}
@@ -54,27 +54,39 @@ class LocalTime extends HTMLElement {
 	}
 }
 
+// timeZone returns the time zone the user has chosen in settings, or
+// undefined to use the time zone of the browser.
+function timeZone(): string | undefined {
+	const meta = document.querySelector<HTMLMetaElement>(
+		'meta[name="time-zone"]',
+	);
+	return meta?.content || undefined;
+}
+
 function localeString(
 	date: Date,
 	dateonly: boolean,
 	timeonly: boolean,
 ): string {
+	const lang = window.navigator.language;
+	const tz = timeZone();
 	if (timeonly) {
-		const today = new Date().toLocaleDateString(window.navigator.language);
-		const ts = date.toLocaleTimeString(window.navigator.language, {
+		const today = new Date().toLocaleDateString(lang, { timeZone: tz });
+		const ts = date.toLocaleTimeString(lang, {
 			hour: "2-digit",
 			minute: "2-digit",
+			timeZone: tz,
 		});
-		const ds = date.toLocaleDateString(window.navigator.language);
+		const ds = date.toLocaleDateString(lang, { timeZone: tz });
 		if (ds === today) {
 			return ts;
 		}
 		return ds;
 	}
 	if (dateonly) {
-		return date.toLocaleDateString(window.navigator.language);
+		return date.toLocaleDateString(lang, { timeZone: tz });
 	}
-	return date.toLocaleString(window.navigator.language);
+	return date.toLocaleString(lang, { timeZone: tz });
 }
 
 if (!customElements.get("local-time")) {
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	// embedded so that user time zones load in images without tzdata
+	_ "time/tzdata"
 
 	"github.com/lionpuro/neverexpire/api"
 	"github.com/lionpuro/neverexpire/auth"
@@ -38,10 +40,12 @@ func main() {
 
 	mux := http.NewServeMux()
 
-	webh := web.NewHandler(logger, us, hs, ks, ns, auth)
+	mailer := notifications.NewMailer(conf)
+
+	webh := web.NewHandler(logger, us, hs, ks, ns, mailer, conf.AppURL, auth)
 
 	mux.Handle("/", web.NewRouter(webh))
-	api.New(mux, logger, us, hs, ks).Register()
+	api.New(mux, logger, us, hs, ks, ns).Register()
 
 	srv := newServer(3000, mux)
 
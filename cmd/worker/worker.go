@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"time"
+	// embedded so that user time zones load in images without tzdata
+	_ "time/tzdata"
 
 	"github.com/lionpuro/neverexpire/config"
 	"github.com/lionpuro/neverexpire/db"
@@ -25,7 +27,8 @@ func main() {
 	ns := notifications.NewService(notifications.NewRepository(pool))
 	logger := logging.NewLogger()
 	updater := hosts.NewWorker(30*time.Minute, hs, logger)
-	notifier := notifications.NewWorker(60*time.Second, ns, hs, logger)
+	mailer := notifications.NewMailer(conf)
+	notifier := notifications.NewWorker(60*time.Second, ns, hs, mailer, conf.AppURL, logger)
 
 	fmt.Println("Starting notification service...")
 	go notifier.Start(context.Background())
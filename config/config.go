@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
 type Config struct {
@@ -11,7 +12,15 @@ type Config struct {
 	OAuthGoogleCallbackURL,
 	RedisURL,
 	RedisPassword,
-	PostgresURL string
+	PostgresURL,
+	// AppURL is the public URL of the app used in links sent by email and
+	// in notification messages.
+	AppURL,
+	SMTPHost,
+	SMTPPort,
+	SMTPUsername,
+	SMTPPassword,
+	SMTPFrom string
 }
 
 func FromEnv() *Config {
@@ -31,6 +40,22 @@ func FromEnv() *Config {
 		RedisURL:                rdurl,
 		RedisPassword:           os.Getenv("REDIS_PASSWORD"),
 		PostgresURL:             pgurl,
+		AppURL:                  appURL(),
+		SMTPHost:                os.Getenv("SMTP_HOST"),
+		SMTPPort:                os.Getenv("SMTP_PORT"),
+		SMTPUsername:            os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:            os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:                os.Getenv("SMTP_FROM"),
 	}
 	return conf
 }
+
+func appURL() string {
+	if url := os.Getenv("APP_URL"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	if os.Getenv("APP_ENV") == "production" {
+		return "https://neverexpire.lionpuro.com"
+	}
+	return "http://localhost:3000"
+}
@@ -0,0 +1,11 @@
+alter table hosts
+drop constraint uq_hosts_hostname_port;
+
+/* hosts on non-default ports can't be represented without the port column */
+delete from hosts where port != 443;
+
+alter table hosts
+add constraint uq_hosts_hostname unique (hostname);
+
+alter table hosts
+drop column port;
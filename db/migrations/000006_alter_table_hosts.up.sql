@@ -0,0 +1,8 @@
+alter table hosts
+add port int not null default 443;
+
+alter table hosts
+drop constraint uq_hosts_hostname;
+
+alter table hosts
+add constraint uq_hosts_hostname_port unique (hostname, port);
@@ -0,0 +1,11 @@
+alter table hosts
+drop constraint uq_hosts_hostname_port_protocol;
+
+/* hosts using starttls can't be represented without the protocol column */
+delete from hosts where protocol != 'tls';
+
+alter table hosts
+add constraint uq_hosts_hostname_port unique (hostname, port);
+
+alter table hosts
+drop column protocol;
@@ -0,0 +1,8 @@
+alter table hosts
+add protocol text not null default 'tls';
+
+alter table hosts
+drop constraint uq_hosts_hostname_port;
+
+alter table hosts
+add constraint uq_hosts_hostname_port_protocol unique (hostname, port, protocol);
@@ -0,0 +1,2 @@
+alter table hosts
+add chain jsonb not null default '[]';
@@ -0,0 +1,2 @@
+alter table hosts
+drop column error_reason;
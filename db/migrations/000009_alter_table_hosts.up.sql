@@ -0,0 +1,2 @@
+alter table hosts
+add error_reason text not null default '';
@@ -0,0 +1,3 @@
+alter table hosts
+drop column endpoints,
+drop column fingerprint_mismatch;
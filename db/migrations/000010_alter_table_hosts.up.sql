@@ -0,0 +1,3 @@
+alter table hosts
+add endpoints jsonb not null default '[]',
+add fingerprint_mismatch boolean not null default false;
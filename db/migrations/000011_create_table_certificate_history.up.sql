@@ -0,0 +1,19 @@
+create table if not exists certificate_history (
+	id          int primary key generated by default as identity,
+	host_id     int not null,
+	fingerprint text not null,
+	subject     text not null,
+	issuer      text not null,
+	dns_names   text not null,
+	not_before  timestamp not null,
+	not_after   timestamp not null,
+	first_seen  timestamp not null default (now() at time zone 'utc'),
+	last_seen   timestamp not null default (now() at time zone 'utc'),
+	constraint fk_certificate_history_host_id
+		foreign key (host_id)
+		references hosts (id)
+		on delete cascade,
+	constraint uq_certificate_history_host_id_fingerprint
+		unique (host_id, fingerprint)
+);
+create index idx_certificate_history_host_id on certificate_history(host_id);
@@ -0,0 +1,5 @@
+alter table notifications
+drop constraint uq_notifications_user_id_host_id_due;
+
+alter table notifications
+add constraint uq_notifications_user_id_host_id_type_due unique (user_id, host_id, notification_type, due);
@@ -0,0 +1,4 @@
+alter table hosts
+drop column failures,
+drop column failing_since,
+drop column recovered_at;
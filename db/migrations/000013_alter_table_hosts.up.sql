@@ -0,0 +1,4 @@
+alter table hosts
+add failures int not null default 0,
+add failing_since timestamp,
+add recovered_at timestamp;
@@ -0,0 +1,2 @@
+alter table settings
+add failure_threshold int not null default 2;
@@ -0,0 +1,11 @@
+alter table settings
+add reminder_threshold int not null default 0;
+
+update settings
+set reminder_threshold = coalesce(
+	(select max(t) from unnest(reminder_thresholds) as t),
+	0
+);
+
+alter table settings
+drop column reminder_thresholds;
@@ -0,0 +1,8 @@
+alter table settings
+add reminder_thresholds int[] not null default '{1209600}';
+
+update settings
+set reminder_thresholds = array[reminder_threshold];
+
+alter table settings
+drop column reminder_threshold;
@@ -0,0 +1,3 @@
+alter table user_hosts
+drop column reminder_thresholds,
+drop column muted;
@@ -0,0 +1,3 @@
+alter table user_hosts
+add reminder_thresholds int[],
+add muted boolean not null default false;
@@ -0,0 +1,2 @@
+alter table settings
+add webhook_secret text;
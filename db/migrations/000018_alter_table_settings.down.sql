@@ -0,0 +1,5 @@
+alter table settings
+drop column email,
+drop column email_verified_at,
+drop column email_token_hash,
+drop column email_token_expires_at;
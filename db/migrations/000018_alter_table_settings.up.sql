@@ -0,0 +1,5 @@
+alter table settings
+add email text,
+add email_verified_at timestamptz,
+add email_token_hash text,
+add email_token_expires_at timestamptz;
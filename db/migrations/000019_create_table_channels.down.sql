@@ -0,0 +1,29 @@
+alter table settings
+add webhook_url text not null default '',
+add webhook_provider text,
+add webhook_secret text;
+
+update settings s
+set
+	webhook_url = c.url,
+	webhook_provider = c.provider,
+	webhook_secret = c.secret
+from (
+	select distinct on (user_id) user_id, url, provider, secret
+	from channels
+	where provider != 'EMAIL'
+	order by user_id, id
+) c
+where c.user_id = s.user_id;
+
+alter table settings
+alter column webhook_url drop default,
+add constraint ck_settings_webhook_null_link
+check (
+	(webhook_provider is null and webhook_url = '')
+	or (webhook_provider is not null and webhook_url != '')
+);
+
+drop table if exists deliveries;
+drop table if exists routing_rules;
+drop table if exists channels;
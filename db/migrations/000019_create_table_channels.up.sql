@@ -0,0 +1,81 @@
+create table if not exists channels (
+	id         int primary key generated by default as identity,
+	user_id    varchar(255) not null,
+	name       text not null,
+	provider   text not null,
+	url        text not null default '',
+	secret     text,
+	created_at timestamp not null default (now() at time zone 'utc'),
+	constraint fk_channels_user_id
+		foreign key (user_id)
+		references users (id)
+		on delete cascade
+);
+create index idx_channels_user_id on channels(user_id);
+
+/* an empty notification_types array matches every type */
+create table if not exists routing_rules (
+	id                 int primary key generated by default as identity,
+	channel_id         int not null,
+	notification_types int[] not null default '{}',
+	min_severity       int not null default 0,
+	created_at         timestamp not null default (now() at time zone 'utc'),
+	constraint fk_routing_rules_channel_id
+		foreign key (channel_id)
+		references channels (id)
+		on delete cascade
+);
+create index idx_routing_rules_channel_id on routing_rules(channel_id);
+
+create table if not exists deliveries (
+	id              int primary key generated by default as identity,
+	notification_id int not null,
+	channel_id      int not null,
+	attempts        int not null default 0,
+	delivered_at    timestamp,
+	error_message   text,
+	updated_at      timestamp not null default (now() at time zone 'utc'),
+	constraint fk_deliveries_notification_id
+		foreign key (notification_id)
+		references notifications (id)
+		on delete cascade,
+	constraint fk_deliveries_channel_id
+		foreign key (channel_id)
+		references channels (id)
+		on delete cascade,
+	constraint uq_deliveries_notification_id_channel_id
+		unique (notification_id, channel_id)
+);
+
+insert into channels (user_id, name, provider, url, secret)
+select
+	user_id,
+	case webhook_provider
+		when 'DISCORD' then 'Discord'
+		when 'SLACK' then 'Slack'
+		when 'TEAMS' then 'Microsoft Teams'
+		when 'MATTERMOST' then 'Mattermost'
+		when 'GOOGLE_CHAT' then 'Google Chat'
+		when 'NTFY' then 'ntfy'
+		when 'PAGERDUTY' then 'PagerDuty'
+		else 'Webhook'
+	end,
+	webhook_provider,
+	webhook_url,
+	webhook_secret
+from settings
+where webhook_provider is not null and webhook_url != '';
+
+insert into channels (user_id, name, provider)
+select user_id, 'Email', 'EMAIL'
+from settings
+where email is not null and email_verified_at is not null;
+
+insert into routing_rules (channel_id)
+select id from channels;
+
+alter table settings
+drop constraint ck_settings_webhook_null_link,
+drop column webhook_url,
+drop column webhook_provider,
+drop column webhook_secret;
@@ -0,0 +1,5 @@
+drop index if exists idx_deliveries_next_attempt_at;
+
+alter table deliveries
+drop column failed_at,
+drop column next_attempt_at;
@@ -0,0 +1,11 @@
+alter table deliveries
+add next_attempt_at timestamp not null default (now() at time zone 'utc'),
+add failed_at timestamp;
+
+/* deliveries that used up the three attempts of the old schedule */
+update deliveries
+set failed_at = updated_at
+where delivered_at is null and attempts >= 3;
+
+create index idx_deliveries_next_attempt_at on deliveries(next_attempt_at)
+where delivered_at is null and failed_at is null;
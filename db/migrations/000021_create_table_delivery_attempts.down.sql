@@ -0,0 +1,4 @@
+alter table channels
+drop column consecutive_failures;
+
+drop table if exists delivery_attempts;
@@ -0,0 +1,22 @@
+create table if not exists delivery_attempts (
+	id            int primary key generated by default as identity,
+	delivery_id   int not null,
+	channel_id    int not null,
+	status_code   int,
+	response_body text,
+	latency_ms    int not null default 0,
+	error_message text,
+	created_at    timestamp not null default (now() at time zone 'utc'),
+	constraint fk_delivery_attempts_delivery_id
+		foreign key (delivery_id)
+		references deliveries (id)
+		on delete cascade,
+	constraint fk_delivery_attempts_channel_id
+		foreign key (channel_id)
+		references channels (id)
+		on delete cascade
+);
+create index idx_delivery_attempts_channel_id_created_at on delivery_attempts(channel_id, created_at desc);
+
+alter table channels
+add consecutive_failures int not null default 0;
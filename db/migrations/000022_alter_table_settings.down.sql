@@ -0,0 +1,9 @@
+alter table deliveries
+drop column digest;
+
+alter table settings
+drop constraint ck_settings_digest_weekday,
+drop constraint ck_settings_digest_hour,
+drop column digest_weekday,
+drop column digest_hour,
+drop column digest_frequency;
@@ -0,0 +1,11 @@
+/* digest_frequency: 0 = off, 1 = daily, 2 = weekly */
+alter table settings
+add digest_frequency int not null default 0,
+add digest_hour int not null default 8,
+add digest_weekday int not null default 1,
+add constraint ck_settings_digest_hour check (digest_hour between 0 and 23),
+add constraint ck_settings_digest_weekday check (digest_weekday between 0 and 6);
+
+/* reminders held back until the next digest of the user */
+alter table deliveries
+add digest boolean not null default false;
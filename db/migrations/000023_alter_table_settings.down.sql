@@ -0,0 +1,5 @@
+alter table settings
+drop constraint ck_settings_quiet_hours,
+drop column quiet_hours_end,
+drop column quiet_hours_start,
+drop column time_zone;
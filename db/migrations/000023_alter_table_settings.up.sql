@@ -0,0 +1,10 @@
+/* quiet_hours_start and quiet_hours_end are hours in time_zone, both null when quiet hours are off */
+alter table settings
+add time_zone text not null default 'UTC',
+add quiet_hours_start int,
+add quiet_hours_end int,
+add constraint ck_settings_quiet_hours check (
+	(quiet_hours_start is null) = (quiet_hours_end is null)
+	and quiet_hours_start between 0 and 23
+	and quiet_hours_end between 0 and 23
+);
@@ -0,0 +1,5 @@
+alter table channels
+drop column message_template;
+
+alter table settings
+drop column message_template;
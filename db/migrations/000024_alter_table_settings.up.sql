@@ -0,0 +1,6 @@
+/* message templates of the user and of each channel, null for the default messages */
+alter table settings
+add message_template text;
+
+alter table channels
+add message_template text;
@@ -0,0 +1,9 @@
+alter table deliveries
+drop column suppressed_at;
+
+alter table user_hosts
+drop column acknowledgement_note,
+drop column acknowledged_fingerprint,
+drop column acknowledged_at,
+drop column snoozed_fingerprint,
+drop column snoozed_until;
@@ -0,0 +1,10 @@
+/* snoozes and acknowledgements hold while the host serves the certificate with the fingerprint */
+alter table user_hosts
+add snoozed_until timestamp,
+add snoozed_fingerprint text not null default '',
+add acknowledged_at timestamp,
+add acknowledged_fingerprint text not null default '',
+add acknowledgement_note text not null default '';
+
+alter table deliveries
+add suppressed_at timestamp;
@@ -0,0 +1,4 @@
+drop index if exists idx_user_hosts_tags;
+
+alter table user_hosts
+drop column tags;
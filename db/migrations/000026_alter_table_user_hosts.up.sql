@@ -0,0 +1,4 @@
+alter table user_hosts
+add tags text[] not null default '{}';
+
+create index idx_user_hosts_tags on user_hosts using gin(tags);
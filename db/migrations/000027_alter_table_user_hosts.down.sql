@@ -0,0 +1,2 @@
+alter table user_hosts
+drop column snoozed_at;
@@ -0,0 +1,7 @@
+/* snoozes only hold back notifications that were due when the host was snoozed */
+alter table user_hosts
+add snoozed_at timestamp;
+
+update user_hosts
+set snoozed_at = (now() at time zone 'utc')
+where snoozed_until is not null;
@@ -0,0 +1,159 @@
+package hosts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/sync/errgroup"
+)
+
+// MaxBatch is the largest number of hosts in a batch.
+const MaxBatch = 100
+
+// batchConcurrency is how many certificates of a batch are fetched at once.
+const batchConcurrency = 10
+
+type BatchStatus string
+
+const (
+	BatchCreated         BatchStatus = "created"
+	BatchAlreadyTracking BatchStatus = "already_tracking"
+	BatchUnreachable     BatchStatus = "unreachable"
+	BatchInvalid         BatchStatus = "invalid"
+	BatchDeleted         BatchStatus = "deleted"
+	BatchNotFound        BatchStatus = "not_found"
+	BatchFailed          BatchStatus = "failed"
+)
+
+// BatchResult is the result of a single host of a batch.
+type BatchResult struct {
+	// Host is the host as stored, or the input if it isn't tracked.
+	Host   Host
+	Status BatchStatus
+	// Err is why the host is invalid, unreachable or failed.
+	Err error
+}
+
+// CreateBatch starts tracking each of the hosts that can be reached and
+// returns a result for every input host, in order. Unlike Create, a host that
+// fails doesn't stop the rest from being created.
+func (s *Service) CreateBatch(uid string, input []Host) []BatchResult {
+	results := make([]BatchResult, len(input))
+	seen := make(map[string]bool)
+	pending := make([]int, 0, len(input))
+	for i, in := range input {
+		if in.Protocol == "" {
+			in.Protocol = ProtocolTLS
+		}
+		target := Host{Hostname: in.Hostname, Port: in.Port, Protocol: in.Protocol}
+		results[i].Host = target
+		key := fmt.Sprintf("%s:%d/%s", in.Hostname, in.Port, in.Protocol)
+		if seen[key] {
+			results[i].Status = BatchInvalid
+			results[i].Err = errors.New("duplicate host in batch")
+			continue
+		}
+		seen[key] = true
+		host, err := s.byName(uid, target)
+		switch {
+		case err == nil:
+			results[i] = BatchResult{Host: host, Status: BatchAlreadyTracking}
+		case errors.Is(err, pgx.ErrNoRows):
+			pending = append(pending, i)
+		default:
+			results[i].Status = BatchFailed
+			results[i].Err = err
+		}
+	}
+
+	fetchBatch(results, pending)
+
+	for _, i := range pending {
+		r := &results[i]
+		if r.Status != "" {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		err := s.repo.Create(ctx, uid, []Host{r.Host})
+		cancel()
+		switch {
+		case errors.Is(err, ErrAlreadyTracking):
+			r.Status = BatchAlreadyTracking
+		case err != nil:
+			r.Status = BatchFailed
+			r.Err = err
+			continue
+		default:
+			r.Status = BatchCreated
+		}
+		if host, err := s.byName(uid, r.Host); err == nil {
+			r.Host = host
+		}
+	}
+	return results
+}
+
+// DeleteBatch stops tracking each of the hosts and returns a result for
+// every input host, in order.
+func (s *Service) DeleteBatch(uid string, input []Host) []BatchResult {
+	results := make([]BatchResult, len(input))
+	for i, in := range input {
+		if in.Protocol == "" {
+			in.Protocol = ProtocolTLS
+		}
+		results[i].Host = in
+		host, err := s.byName(uid, in)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			results[i].Status = BatchNotFound
+			continue
+		case err != nil:
+			results[i].Status = BatchFailed
+			results[i].Err = err
+			continue
+		}
+		results[i].Host = host
+		if err := s.Delete(uid, host.ID); err != nil {
+			results[i].Status = BatchFailed
+			results[i].Err = err
+			continue
+		}
+		results[i].Status = BatchDeleted
+	}
+	return results
+}
+
+// fetchBatch fetches the certificates of the pending results, marking the
+// hosts that can't be reached as unreachable.
+func fetchBatch(results []BatchResult, pending []int) {
+	var eg errgroup.Group
+	eg.SetLimit(batchConcurrency)
+	for _, i := range pending {
+		eg.Go(func() error {
+			r := &results[i]
+			host, err := fetch(r.Host)
+			if err != nil {
+				r.Status = BatchUnreachable
+				r.Err = err
+				return nil
+			}
+			if host.Certificate.Status == CertificateStatusOffline {
+				r.Status = BatchUnreachable
+				r.Err = fmt.Errorf("can't connect to %s: %w", host.Address(), host.Certificate.Error)
+				return nil
+			}
+			r.Host = host
+			return nil
+		})
+	}
+	_ = eg.Wait()
+}
+
+func (s *Service) byName(uid string, target Host) (Host, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	return s.repo.ByName(ctx, uid, target.Hostname, target.Port, target.Protocol)
+}
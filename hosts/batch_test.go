@@ -0,0 +1,63 @@
+package hosts
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestFetchBatch(t *testing.T) {
+	cert, err := newTestCertificate("localhost")
+	if err != nil {
+		t.Fatalf("failed to create test certificate: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(serveOnce(t, cert, func(net.Conn) error { return nil }))
+	served, _ := strconv.Atoi(port)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	closed := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	tests := []struct {
+		name     string
+		host     Host
+		expected BatchStatus
+	}{
+		{
+			name:     "unresolvable",
+			host:     Host{Hostname: "does-not-exist.invalid", Port: 443, Protocol: ProtocolTLS},
+			expected: BatchUnreachable,
+		},
+		{
+			name:     "connection refused",
+			host:     Host{Hostname: "127.0.0.1", Port: closed, Protocol: ProtocolTLS},
+			expected: BatchUnreachable,
+		},
+		{
+			name:     "reachable",
+			host:     Host{Hostname: "127.0.0.1", Port: served, Protocol: ProtocolTLS},
+			expected: "",
+		},
+	}
+	results := make([]BatchResult, len(tests))
+	pending := make([]int, len(tests))
+	for i, ts := range tests {
+		results[i].Host = ts.host
+		pending[i] = i
+	}
+	fetchBatch(results, pending)
+	for i, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			r := results[i]
+			if r.Status != ts.expected {
+				t.Errorf("expected status %q, got %q (error: %v)", ts.expected, r.Status, r.Err)
+			}
+			if ts.expected == BatchUnreachable && r.Err == nil {
+				t.Error("expected error and got none")
+			}
+		})
+	}
+}
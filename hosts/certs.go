@@ -2,56 +2,56 @@ package hosts
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"crypto/sha1"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
 	"fmt"
+	"net"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/lionpuro/neverexpire/logging"
 )
 
-func FetchCert(ctx context.Context, hostname string) (*CertificateInfo, error) {
+// probeTimeout bounds the check of a single endpoint, so that an unreachable
+// address can't make the whole check time out.
+const probeTimeout = time.Second * 4
+
+// FetchCert resolves the hostname and reads the certificate presented on each
+// of its addresses. For protocols other than ProtocolTLS the connection is
+// upgraded to TLS with the protocol's STARTTLS handshake first.
+func FetchCert(ctx context.Context, hostname string, port int, protocol Protocol) (*CertificateInfo, error) {
 	errch := make(chan error, 1)
 	result := make(chan CertificateInfo, 1)
 	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
 	go func() {
 		start := time.Now().UTC()
-		conn, err := tls.Dial("tcp", fmt.Sprintf("%s:443", hostname), nil)
+		ips, err := lookupIPs(ctx, hostname)
 		if err != nil {
-			status := errorStatus(err)
-			result <- CertificateInfo{
-				Status:    status,
-				IssuedBy:  "n/a",
-				CheckedAt: start,
-				Error:     mapError(err),
-			}
+			result <- failedProbe(err, "", start)
 			return
 		}
-		defer func() {
-			if err := conn.Close(); err != nil {
-				logging.DefaultLogger().Error("error closing connection", "error", err.Error())
-			}
-		}()
-		state := conn.ConnectionState()
-		cert := state.PeerCertificates[0]
-		status := CertificateStatusInvalid
-		if cert.NotAfter.After(time.Now().UTC()) {
-			status = CertificateStatusHealthy
-		}
-		result <- CertificateInfo{
-			DNSNames:  strings.Join(cert.DNSNames, ", "),
-			IP:        conn.RemoteAddr().String(),
-			ExpiresAt: &cert.NotAfter,
-			IssuedBy:  cert.Issuer.Organization[0],
-			CheckedAt: start,
-			Status:    status,
-			Latency:   int(time.Since(start).Milliseconds()),
-			Signature: fingerprint(cert),
+		probes := make([]CertificateInfo, len(ips))
+		wg := sync.WaitGroup{}
+		for i, ip := range ips {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				probes[i] = probe(ctx, hostname, ip, port, protocol)
+			}()
 		}
+		wg.Wait()
+		result <- combineProbes(probes)
 	}()
 
 	select {
@@ -75,39 +75,252 @@ func FetchCert(ctx context.Context, hostname string) (*CertificateInfo, error) {
 	}
 }
 
+// errNoAddresses is reported when the hostname resolves to no addresses.
+var errNoAddresses = &net.DNSError{Err: "no addresses", IsNotFound: true}
+
+// lookupIPs returns all addresses the hostname resolves to.
+func lookupIPs(ctx context.Context, hostname string) ([]string, error) {
+	if ip := net.ParseIP(hostname); ip != nil {
+		return []string{ip.String()}, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		if ip := addr.IP.String(); !slices.Contains(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	slices.Sort(ips)
+	return ips, nil
+}
+
+// probe reads the certificate presented on a single address of the host, using
+// the hostname for SNI and verification.
+func probe(ctx context.Context, hostname, ip string, port int, protocol Protocol) CertificateInfo {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	start := time.Now().UTC()
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return failedProbe(err, addr, start)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logging.DefaultLogger().Error("error closing connection", "error", err.Error())
+		}
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return failedProbe(err, addr, start)
+		}
+	}
+	if err := startTLS(conn, hostname, protocol); err != nil {
+		return failedProbe(err, addr, start)
+	}
+	// the chain is verified separately to record the certificates and the
+	// reason for the failure even if the verification fails
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         hostname,
+		InsecureSkipVerify: true,
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return failedProbe(err, addr, start)
+	}
+	info := certificateInfo(tlsConn.ConnectionState().PeerCertificates, hostname, nil)
+	info.IP = addr
+	info.CheckedAt = start
+	info.Latency = int(time.Since(start).Milliseconds())
+	return info
+}
+
+func failedProbe(err error, addr string, start time.Time) CertificateInfo {
+	return CertificateInfo{
+		IP:        addr,
+		Status:    errorStatus(err),
+		IssuedBy:  "n/a",
+		CheckedAt: start,
+		Error:     mapError(err),
+	}
+}
+
+// combineProbes reports the most pressing result of the probed endpoints as
+// the result of the host, recording the result of each endpoint alongside it.
+func combineProbes(probes []CertificateInfo) CertificateInfo {
+	if len(probes) == 0 {
+		return failedProbe(errNoAddresses, "", time.Now().UTC())
+	}
+	info := probes[0]
+	for _, p := range probes[1:] {
+		if morePressing(p, info) {
+			info = p
+		}
+	}
+	info.Endpoints = make([]Endpoint, len(probes))
+	var fingerprints []string
+	for i, p := range probes {
+		info.Endpoints[i] = newEndpoint(p)
+		if p.Signature != "" && !slices.Contains(fingerprints, p.Signature) {
+			fingerprints = append(fingerprints, p.Signature)
+		}
+	}
+	info.FingerprintMismatch = len(fingerprints) > 1
+	return info
+}
+
+// morePressing reports whether the result a needs attention before b. Endpoints
+// that presented a certificate take precedence over unreachable ones, so that
+// an address that can't be routed from here doesn't hide the certificates.
+func morePressing(a, b CertificateInfo) bool {
+	if (a.Signature != "") != (b.Signature != "") {
+		return a.Signature != ""
+	}
+	if a.Status != b.Status {
+		return a.Status < b.Status
+	}
+	if a.ExpiresAt == nil || b.ExpiresAt == nil {
+		return false
+	}
+	return a.ExpiresAt.Before(*b.ExpiresAt)
+}
+
+func newEndpoint(info CertificateInfo) Endpoint {
+	e := Endpoint{
+		IP:          info.IP,
+		Status:      info.Status,
+		Fingerprint: info.Signature,
+		ExpiresAt:   info.ExpiresAt,
+		Reason:      info.Reason,
+		Latency:     info.Latency,
+	}
+	if info.Error != nil {
+		e.Error = info.Error.Error()
+	}
+	return e
+}
+
+// certificateInfo builds the certificate information from the certificates
+// presented by a host, verifying them against roots.
+func certificateInfo(certs []*x509.Certificate, hostname string, roots *x509.CertPool) CertificateInfo {
+	if len(certs) == 0 {
+		return CertificateInfo{
+			Status:   CertificateStatusInvalid,
+			IssuedBy: "n/a",
+			Reason:   ReasonOther,
+			Error:    ErrCertInvalid,
+		}
+	}
+	cert := certs[0]
+	chain := make([]Certificate, len(certs))
+	for i, c := range certs {
+		chain[i] = newCertificate(c)
+	}
+	verified, reason := verifyChain(certs, hostname, roots, time.Now().UTC())
+	if verified == nil {
+		verified = certs
+	}
+	// the chain is only as valid as its first expiring certificate
+	expires := cert.NotAfter
+	for _, c := range verified {
+		if c.NotAfter.Before(expires) {
+			expires = c.NotAfter
+		}
+	}
+	info := CertificateInfo{
+		DNSNames:  strings.Join(cert.DNSNames, ", "),
+		ExpiresAt: &expires,
+		IssuedBy:  issuerName(cert),
+		Status:    CertificateStatusHealthy,
+		Signature: fingerprint(cert),
+		Chain:     chain,
+	}
+	if reason != ReasonNone {
+		info.Status = CertificateStatusInvalid
+		info.Reason = reason
+		info.Error = ErrCertInvalid
+	}
+	return info
+}
+
 func fingerprint(cert *x509.Certificate) string {
 	fingerprint := sha1.Sum(cert.Raw)
 	return fmt.Sprintf("%x", fingerprint)
 }
 
+func newCertificate(cert *x509.Certificate) Certificate {
+	return Certificate{
+		Subject:     pkixName(cert.Subject),
+		Issuer:      pkixName(cert.Issuer),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		Fingerprint: fingerprint(cert),
+		KeyType:     keyType(cert),
+	}
+}
+
+func pkixName(name pkix.Name) string {
+	if name.CommonName != "" {
+		return name.CommonName
+	}
+	return name.String()
+}
+
+func issuerName(cert *x509.Certificate) string {
+	if org := cert.Issuer.Organization; len(org) > 0 {
+		return org[0]
+	}
+	return pkixName(cert.Issuer)
+}
+
+func keyType(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
+
 func errorStatus(err error) CertificateStatus {
+	var dnsErr *net.DNSError
 	switch {
-	case strings.Contains(err.Error(), "tls: failed to verify"):
-		return CertificateStatusInvalid
 	case
-		strings.Contains(err.Error(), "connection refused"),
-		strings.Contains(err.Error(), "no such host"),
-		strings.Contains(err.Error(), "Temporary failure in name resolution"):
+		errors.Is(err, ErrStartTLS),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH),
+		errors.As(err, &dnsErr):
 		return CertificateStatusOffline
 	}
 	return CertificateStatusUnknown
 }
 
 func mapError(err error) Error {
-	contains := func(s string) bool {
-		return strings.Contains(err.Error(), s)
-	}
+	var (
+		dnsErr *net.DNSError
+		netErr net.Error
+	)
 	switch {
 	case errors.Is(err, context.DeadlineExceeded):
 		return ErrConnTimedout
-	case contains("tls: failed to verify"):
-		return ErrCertInvalid
-	case contains("connection refused"):
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrConnTimedout
+	case errors.Is(err, ErrStartTLS):
+		return ErrStartTLS
+	case errors.Is(err, syscall.ECONNREFUSED):
 		return ErrConnRefused
 	case
-		contains("no such host"),
-		contains("dial tcp: lookup"),
-		contains("failure in name resolution"):
+		errors.As(err, &dnsErr),
+		errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH):
 		return ErrConn
 	default:
 		return ErrUnknown
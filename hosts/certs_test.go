@@ -0,0 +1,113 @@
+package hosts
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestNewCertificate(t *testing.T) {
+	tlsCert, err := newTestCertificate("localhost")
+	if err != nil {
+		t.Fatalf("failed to create test certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(tlsCert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse test certificate: %v", err)
+	}
+	result := newCertificate(cert)
+	if result.Subject != "localhost" {
+		t.Errorf("incorrect subject: expected localhost, got %s", result.Subject)
+	}
+	if result.Issuer != "localhost" {
+		t.Errorf("incorrect issuer: expected localhost, got %s", result.Issuer)
+	}
+	if result.KeyType != "ECDSA P-256" {
+		t.Errorf("incorrect key type: expected ECDSA P-256, got %s", result.KeyType)
+	}
+	if result.Fingerprint != fingerprint(cert) {
+		t.Errorf("incorrect fingerprint: expected %s, got %s", fingerprint(cert), result.Fingerprint)
+	}
+	if !result.NotAfter.Equal(cert.NotAfter) || !result.NotBefore.Equal(cert.NotBefore) {
+		t.Error("validity period doesn't match the certificate")
+	}
+	if iss := issuerName(cert); iss != "neverexpire test" {
+		t.Errorf("incorrect issuer name: expected neverexpire test, got %s", iss)
+	}
+}
+
+func TestCombineProbes(t *testing.T) {
+	now := time.Now().UTC()
+	soon, later := now.Add(24*time.Hour), now.Add(60*24*time.Hour)
+	healthy := CertificateInfo{IP: "192.0.2.1:443", Status: CertificateStatusHealthy, Signature: "ab12", ExpiresAt: &later}
+	stale := CertificateInfo{IP: "192.0.2.2:443", Status: CertificateStatusHealthy, Signature: "cd34", ExpiresAt: &soon}
+	invalid := CertificateInfo{IP: "192.0.2.3:443", Status: CertificateStatusInvalid, Signature: "ef56", ExpiresAt: &later}
+	offline := CertificateInfo{IP: "[2001:db8::1]:443", Status: CertificateStatusOffline, Error: ErrConnRefused}
+
+	tests := []struct {
+		name     string
+		probes   []CertificateInfo
+		primary  string
+		mismatch bool
+	}{
+		{
+			name:     "Same certificate",
+			probes:   []CertificateInfo{healthy, {IP: "192.0.2.4:443", Status: CertificateStatusHealthy, Signature: "ab12", ExpiresAt: &later}},
+			primary:  healthy.IP,
+			mismatch: false,
+		},
+		{
+			name:     "Stale certificate",
+			probes:   []CertificateInfo{healthy, stale},
+			primary:  stale.IP,
+			mismatch: true,
+		},
+		{
+			name:     "Invalid certificate",
+			probes:   []CertificateInfo{stale, invalid},
+			primary:  invalid.IP,
+			mismatch: true,
+		},
+		{
+			name:     "Unreachable address",
+			probes:   []CertificateInfo{offline, healthy},
+			primary:  healthy.IP,
+			mismatch: false,
+		},
+		{
+			name:     "All unreachable",
+			probes:   []CertificateInfo{offline},
+			primary:  offline.IP,
+			mismatch: false,
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			result := combineProbes(ts.probes)
+			if result.IP != ts.primary {
+				t.Errorf("expected primary endpoint %s, got %s", ts.primary, result.IP)
+			}
+			if result.FingerprintMismatch != ts.mismatch {
+				t.Errorf("expected mismatch %v, got %v", ts.mismatch, result.FingerprintMismatch)
+			}
+			if len(result.Endpoints) != len(ts.probes) {
+				t.Fatalf("expected %d endpoints, got %d", len(ts.probes), len(result.Endpoints))
+			}
+			for i, e := range result.Endpoints {
+				if e.IP != ts.probes[i].IP {
+					t.Errorf("expected endpoint %s, got %s", ts.probes[i].IP, e.IP)
+				}
+			}
+		})
+	}
+}
+
+func TestCombineProbesNoAddresses(t *testing.T) {
+	result := combineProbes(nil)
+	if result.Status != CertificateStatusOffline {
+		t.Errorf("expected status %v, got %v", CertificateStatusOffline, result.Status)
+	}
+	if result.Error != ErrConn {
+		t.Errorf("expected error %q, got %q", ErrConn, result.Error)
+	}
+}
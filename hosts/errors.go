@@ -1,5 +1,11 @@
 package hosts
 
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
 type Error string
 
 func (e Error) Error() string {
@@ -12,4 +18,22 @@ const (
 	ErrConnTimedout = Error("connection timed out")
 	ErrConnRefused  = Error("connection refused")
 	ErrCertInvalid  = Error("invalid certificate")
+	ErrStartTLS     = Error("STARTTLS negotiation failed")
+	// ErrAlreadyTracking is returned when creating a host the user already
+	// tracks.
+	ErrAlreadyTracking = Error("already tracking")
 )
+
+// RateLimitError is returned when a user checks hosts too often.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many checks, try again in %d seconds", e.Seconds())
+}
+
+// Seconds returns RetryAfter in whole seconds, rounded up.
+func (e *RateLimitError) Seconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
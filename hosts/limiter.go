@@ -0,0 +1,60 @@
+package hosts
+
+import (
+	"sync"
+	"time"
+)
+
+// limiter limits how often each user can do something. A user can make a
+// burst of requests, after which one more is allowed every interval.
+type limiter struct {
+	mu       sync.Mutex
+	burst    int
+	interval time.Duration
+	users    map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(burst int, interval time.Duration) *limiter {
+	return &limiter{
+		burst:    burst,
+		interval: interval,
+		users:    make(map[string]*bucket),
+	}
+}
+
+// allow reports whether the user can make a request at now. If not, it
+// returns how long the user has to wait.
+func (l *limiter) allow(userID string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.users[userID]
+	if !ok {
+		if len(l.users) >= 1024 {
+			l.prune(now)
+		}
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.users[userID] = b
+	}
+	b.tokens = min(float64(l.burst), b.tokens+float64(now.Sub(b.last))/float64(l.interval))
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(l.interval))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune forgets the users who could make a full burst of requests again.
+func (l *limiter) prune(now time.Time) {
+	full := time.Duration(l.burst) * l.interval
+	for id, b := range l.users {
+		if now.Sub(b.last) >= full {
+			delete(l.users, id)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package hosts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2025, 6, 4, 10, 30, 0, 0, time.UTC)
+	l := newLimiter(2, 10*time.Second)
+	tests := []struct {
+		name     string
+		userID   string
+		at       time.Time
+		expected bool
+		wait     time.Duration
+	}{
+		{name: "first", userID: "a", at: now, expected: true},
+		{name: "burst", userID: "a", at: now, expected: true},
+		{name: "over burst", userID: "a", at: now.Add(4 * time.Second), expected: false, wait: 6 * time.Second},
+		{name: "other user", userID: "b", at: now.Add(4 * time.Second), expected: true},
+		{name: "refilled", userID: "a", at: now.Add(10 * time.Second), expected: true},
+		{name: "empty again", userID: "a", at: now.Add(10 * time.Second), expected: false, wait: 10 * time.Second},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			ok, wait := l.allow(ts.userID, ts.at)
+			if ok != ts.expected {
+				t.Errorf("expected %v, got %v", ts.expected, ok)
+			}
+			if wait != ts.wait {
+				t.Errorf("expected to wait %v, got %v", ts.wait, wait)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package hosts
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultListLimit is the page size of a list when no limit is given.
+	DefaultListLimit = 100
+	// MaxListLimit is the largest page size of a list.
+	MaxListLimit = 1000
+)
+
+const (
+	ErrInvalidCursor = Error("invalid cursor")
+	ErrInvalidSort   = Error("invalid sort")
+)
+
+// ListOptions filter, sort and paginate the hosts of a user. Zero values
+// don't filter.
+type ListOptions struct {
+	Statuses      []CertificateStatus
+	ExpiresBefore *time.Time
+	// Issuer matches the issuer of the certificate, ignoring case.
+	Issuer string
+	// Hostname matches hostnames that contain it, ignoring case.
+	Hostname string
+	Tag      string
+	// Sort is one of ListSorts, or "status" if empty.
+	Sort string
+	// Cursor is the NextCursor of the previous page, or empty for the first
+	// page.
+	Cursor string
+	Limit  int
+}
+
+// HostList is a page of hosts.
+type HostList struct {
+	Hosts []Host
+	// Total is the number of hosts matching the filters on every page.
+	Total int
+	// NextCursor points to the next page, or is empty on the last page.
+	NextCursor string
+}
+
+// ListSorts are the sorts of a host list. Sorts prefixed with "-" are
+// descending.
+var ListSorts = []string{
+	"status", "-status",
+	"expires_at", "-expires_at",
+	"hostname", "-hostname",
+	"checked_at", "-checked_at",
+}
+
+type keyKind int
+
+const (
+	keyInt keyKind = iota
+	keyTime
+	keyText
+)
+
+// cast returns the type a value of the kind is compared as.
+func (k keyKind) cast() string {
+	switch k {
+	case keyInt:
+		return "int"
+	case keyTime:
+		return "timestamp"
+	default:
+		return "text"
+	}
+}
+
+// sortKey is a column a list is sorted by. Lists are always sorted by the ID
+// of the host last, so that every host has a unique position.
+type sortKey struct {
+	expr  string
+	kind  keyKind
+	value func(Host) string
+}
+
+// noExpiry sorts hosts without an expiry after the others.
+var noExpiry = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+var (
+	statusKey = sortKey{
+		expr: fmt.Sprintf(
+			"COALESCE(array_position(array[%d, %d, %d], h.status), 4)",
+			CertificateStatusUnknown,
+			CertificateStatusOffline,
+			CertificateStatusInvalid,
+		),
+		kind: keyInt,
+		value: func(h Host) string {
+			return strconv.Itoa(statusRank(h.Certificate.Status))
+		},
+	}
+	expiresKey = sortKey{
+		expr: fmt.Sprintf("COALESCE(h.expires_at, '%s')", noExpiry.Format(time.DateOnly)),
+		kind: keyTime,
+		value: func(h Host) string {
+			if h.Certificate.ExpiresAt == nil {
+				return noExpiry.Format(time.RFC3339Nano)
+			}
+			return h.Certificate.ExpiresAt.Format(time.RFC3339Nano)
+		},
+	}
+	hostnameKey = sortKey{
+		expr:  "h.hostname",
+		kind:  keyText,
+		value: func(h Host) string { return h.Hostname },
+	}
+	portKey = sortKey{
+		expr:  "h.port",
+		kind:  keyInt,
+		value: func(h Host) string { return strconv.Itoa(h.Port) },
+	}
+	checkedKey = sortKey{
+		expr:  "h.checked_at",
+		kind:  keyTime,
+		value: func(h Host) string { return h.Certificate.CheckedAt.Format(time.RFC3339Nano) },
+	}
+)
+
+var sortKeys = map[string][]sortKey{
+	"status":     {statusKey, expiresKey, hostnameKey, portKey},
+	"expires_at": {expiresKey, hostnameKey, portKey},
+	"hostname":   {hostnameKey, portKey},
+	"checked_at": {checkedKey},
+}
+
+// statusRank is the position of a status in the default sort, matching the
+// expression of statusKey.
+func statusRank(s CertificateStatus) int {
+	switch s {
+	case CertificateStatusUnknown:
+		return 1
+	case CertificateStatusOffline:
+		return 2
+	case CertificateStatusInvalid:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// listSort returns the keys and direction of a sort.
+func listSort(sort string) ([]sortKey, bool, error) {
+	name, desc := strings.CutPrefix(sort, "-")
+	keys, ok := sortKeys[name]
+	if !ok {
+		return nil, false, ErrInvalidSort
+	}
+	return keys, desc, nil
+}
+
+// cursor is the position of the last host of a page.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	ID     int      `json:"id"`
+}
+
+func encodeCursor(sort string, keys []sortKey, h Host) string {
+	c := cursor{Sort: sort, ID: h.ID}
+	for _, k := range keys {
+		c.Values = append(c.Values, k.value(h))
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor returns the values of the keys of a cursor, followed by the ID
+// of the host, which is compared as keyInt.
+func decodeCursor(s, sort string, keys []sortKey) ([]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sort || len(c.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+	values := make([]any, 0, len(keys)+1)
+	for i, k := range keys {
+		var v any
+		var err error
+		switch k.kind {
+		case keyInt:
+			v, err = strconv.Atoi(c.Values[i])
+		case keyTime:
+			v, err = time.Parse(time.RFC3339Nano, c.Values[i])
+		default:
+			v = c.Values[i]
+		}
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values = append(values, v)
+	}
+	return append(values, c.ID), nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// IsListError reports whether err is caused by invalid list options.
+func IsListError(err error) bool {
+	return errors.Is(err, ErrInvalidCursor) || errors.Is(err, ErrInvalidSort)
+}
@@ -0,0 +1,105 @@
+package hosts
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	expires := time.Date(2025, 7, 1, 12, 30, 15, 123456000, time.UTC)
+	h := Host{
+		ID:       42,
+		Hostname: "example.com",
+		Port:     8443,
+		Certificate: CertificateInfo{
+			Status:    CertificateStatusInvalid,
+			ExpiresAt: &expires,
+		},
+	}
+	tests := []struct {
+		name     string
+		sort     string
+		host     Host
+		expected []any
+	}{
+		{
+			name:     "status",
+			sort:     "status",
+			host:     h,
+			expected: []any{3, expires, "example.com", 8443, 42},
+		},
+		{
+			name:     "descending hostname",
+			sort:     "-hostname",
+			host:     h,
+			expected: []any{"example.com", 8443, 42},
+		},
+		{
+			name:     "no expiry",
+			sort:     "expires_at",
+			host:     Host{ID: 7, Hostname: "example.org", Port: 443},
+			expected: []any{noExpiry, "example.org", 443, 7},
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			keys, _, err := listSort(ts.sort)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c := encodeCursor(ts.sort, keys, ts.host)
+			values, err := decodeCursor(c, ts.sort, keys)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.EqualFunc(values, ts.expected, func(a, b any) bool {
+				if at, ok := a.(time.Time); ok {
+					bt, ok := b.(time.Time)
+					return ok && at.Equal(bt)
+				}
+				return a == b
+			}) {
+				t.Errorf("expected %v, got %v", ts.expected, values)
+			}
+		})
+	}
+}
+
+func TestInvalidCursor(t *testing.T) {
+	keys, _, _ := listSort("hostname")
+	valid := encodeCursor("hostname", keys, Host{ID: 1, Hostname: "example.com"})
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+	}{
+		{
+			name:   "not base64",
+			cursor: "not a cursor!",
+			sort:   "hostname",
+		},
+		{
+			name:   "not JSON",
+			cursor: "bm90IGpzb24",
+			sort:   "hostname",
+		},
+		{
+			name:   "different sort",
+			cursor: valid,
+			sort:   "-hostname",
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			keys, _, err := listSort(ts.sort)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := decodeCursor(ts.cursor, ts.sort, keys); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("expected %v, got %v", ErrInvalidCursor, err)
+			}
+		})
+	}
+}
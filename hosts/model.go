@@ -1,13 +1,81 @@
 package hosts
 
 import (
+	"fmt"
 	"time"
+
+	"github.com/lionpuro/neverexpire/users"
 )
 
+const DefaultPort = 443
+
 type Host struct {
-	ID          int    `db:"id"`
-	Hostname    string `db:"hostname"`
+	ID          int      `db:"id"`
+	Hostname    string   `db:"hostname"`
+	Port        int      `db:"port"`
+	Protocol    Protocol `db:"protocol"`
 	Certificate CertificateInfo
+	// Settings are the notification settings of the user the host was read
+	// for.
+	Settings HostSettings
+}
+
+// HostSettings are the notification settings of a host for a single user.
+type HostSettings struct {
+	// ReminderThresholds overrides the reminder thresholds of the user
+	// unless nil.
+	ReminderThresholds []int `db:"reminder_thresholds"`
+	Muted              bool  `db:"muted"`
+	// Tags are labels the user has given the host.
+	Tags []string `db:"tags"`
+	// SnoozedAt is when the user snoozed the host, and SnoozedUntil when the
+	// snooze ends, or both are nil if the user hasn't snoozed it. The snooze
+	// only holds while the host serves the certificate with
+	// SnoozedFingerprint.
+	SnoozedAt          *time.Time `db:"snoozed_at"`
+	SnoozedUntil       *time.Time `db:"snoozed_until"`
+	SnoozedFingerprint string     `db:"snoozed_fingerprint"`
+	// AcknowledgedAt is when the user acknowledged the notifications about
+	// the host, or nil if they haven't. The acknowledgement only holds while
+	// the host serves the certificate with AcknowledgedFingerprint.
+	AcknowledgedAt          *time.Time `db:"acknowledged_at"`
+	AcknowledgedFingerprint string     `db:"acknowledged_fingerprint"`
+	AcknowledgementNote     string     `db:"acknowledgement_note"`
+}
+
+// Snoozed reports whether the host is snoozed at t.
+func (h Host) Snoozed(t time.Time) bool {
+	s := h.Settings
+	return s.SnoozedUntil != nil &&
+		t.Before(*s.SnoozedUntil) &&
+		s.SnoozedFingerprint == h.Certificate.Signature
+}
+
+// Acknowledged reports whether the acknowledgement of the host still holds.
+func (h Host) Acknowledged() bool {
+	s := h.Settings
+	return s.AcknowledgedAt != nil && s.AcknowledgedFingerprint == h.Certificate.Signature
+}
+
+// ReminderDays returns the reminder thresholds in days, or nil if the host
+// uses the thresholds of the user.
+func (s HostSettings) ReminderDays() []int {
+	if s.ReminderThresholds == nil {
+		return nil
+	}
+	days := make([]int, len(s.ReminderThresholds))
+	for i, sec := range s.ReminderThresholds {
+		days[i] = sec / (24 * 60 * 60)
+	}
+	return days
+}
+
+// Address returns the hostname, followed by the port if it isn't the default.
+func (h Host) Address() string {
+	if h.Port == 0 || h.Port == DefaultPort {
+		return h.Hostname
+	}
+	return fmt.Sprintf("%s:%d", h.Hostname, h.Port)
 }
 
 type CertificateInfo struct {
@@ -19,15 +87,96 @@ type CertificateInfo struct {
 	CheckedAt time.Time         `db:"checked_at"`
 	Latency   int               `db:"latency"`
 	Signature string            `db:"signature"`
+	Chain     []Certificate     `db:"chain"`
+	Reason    Reason            `db:"error_reason"`
 	Error     error             `db:"-"`
+	// Endpoints holds the result of each address the hostname resolves to.
+	Endpoints []Endpoint `db:"endpoints"`
+	// FingerprintMismatch is set when the endpoints serve different
+	// certificates.
+	FingerprintMismatch bool `db:"fingerprint_mismatch"`
+}
+
+// Certificate describes a single certificate in the chain presented by a host,
+// starting from the leaf.
+type Certificate struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	Fingerprint string    `json:"fingerprint"`
+	KeyType     string    `json:"key_type"`
+}
+
+// HistoryEntry is a leaf certificate that has been seen on a host.
+type HistoryEntry struct {
+	Fingerprint string    `json:"fingerprint"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	DNSNames    string    `json:"dns_names"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// Endpoint is the result of checking a single address of a host.
+type Endpoint struct {
+	IP          string            `json:"ip"`
+	Status      CertificateStatus `json:"status"`
+	Fingerprint string            `json:"fingerprint"`
+	ExpiresAt   *time.Time        `json:"expires_at"`
+	Reason      Reason            `json:"reason,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Latency     int               `json:"latency"`
+}
+
+// Recipient is a user to notify about a host.
+type Recipient struct {
+	UserID string
+	// Email is the verified email address of the user, if any.
+	Email string
+	// TimeZone is the IANA name of the time zone of the user.
+	TimeZone string
+}
+
+// Location returns the time zone of the user, or UTC if it can't be loaded.
+func (r Recipient) Location() *time.Location {
+	return users.LoadLocation(r.TimeZone)
 }
 
 type NotifiableHost struct {
-	Host       Host
-	UserID     string
-	WebhookURL string
-	Threshold  int
-	Attempts   int
+	Host Host
+	Recipient
+	Threshold int
+}
+
+// RenewedHost is a host that started serving a new certificate, along with
+// the user tracking it.
+type RenewedHost struct {
+	Host Host
+	Recipient
+	Previous HistoryEntry
+	Current  HistoryEntry
+}
+
+// FailingHost is a host whose checks have failed at least as many times in a
+// row as the user tracking it has configured, or that recovered from such a
+// failure.
+type FailingHost struct {
+	Host Host
+	Recipient
+	Failures     int
+	FailingSince time.Time
+	RecoveredAt  *time.Time
+	// Notified are the types of the failure notifications the user has been
+	// sent about the failure that started at FailingSince.
+	Notified []int
+}
+
+// Failing reports whether the check of the host failed.
+func (c CertificateInfo) Failing() bool {
+	return c.Status != CertificateStatusHealthy
 }
 
 func (c CertificateInfo) TimeLeft() time.Duration {
@@ -48,6 +197,22 @@ const (
 	CertificateStatusHealthy
 )
 
+// NewCertificateStatus returns the status with the name, as returned by
+// String.
+func NewCertificateStatus(input string) (CertificateStatus, bool) {
+	for _, s := range []CertificateStatus{
+		CertificateStatusUnknown,
+		CertificateStatusOffline,
+		CertificateStatusInvalid,
+		CertificateStatusHealthy,
+	} {
+		if s.String() == input {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
 func (s CertificateStatus) String() string {
 	switch s {
 	case CertificateStatusOffline:
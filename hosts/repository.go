@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/lionpuro/neverexpire/db"
@@ -24,6 +25,8 @@ func (r *Repository) ByID(ctx context.Context, userID string, id int) (Host, err
 	SELECT
 		h.id,
 		h.hostname,
+		h.port,
+		h.protocol,
 		h.dns_names,
 		h.ip_address,
 		h.issued_by,
@@ -32,7 +35,20 @@ func (r *Repository) ByID(ctx context.Context, userID string, id int) (Host, err
 		h.checked_at,
 		h.latency,
 		h.signature,
-		h.error_message
+		h.chain,
+		h.error_reason,
+		h.endpoints,
+		h.fingerprint_mismatch,
+		h.error_message,
+		uh.reminder_thresholds,
+		uh.muted,
+		uh.tags,
+		uh.snoozed_at,
+		uh.snoozed_until,
+		uh.snoozed_fingerprint,
+		uh.acknowledged_at,
+		uh.acknowledged_fingerprint,
+		uh.acknowledgement_note
 	FROM hosts h
 	INNER JOIN user_hosts uh
 		ON h.id = uh.host_id
@@ -42,6 +58,8 @@ func (r *Repository) ByID(ctx context.Context, userID string, id int) (Host, err
 	err := row.Scan(
 		&result.ID,
 		&result.Hostname,
+		&result.Port,
+		&result.Protocol,
 		&result.Certificate.DNSNames,
 		&result.Certificate.IP,
 		&result.Certificate.IssuedBy,
@@ -50,7 +68,20 @@ func (r *Repository) ByID(ctx context.Context, userID string, id int) (Host, err
 		&result.Certificate.CheckedAt,
 		&result.Certificate.Latency,
 		&result.Certificate.Signature,
+		&result.Certificate.Chain,
+		&result.Certificate.Reason,
+		&result.Certificate.Endpoints,
+		&result.Certificate.FingerprintMismatch,
 		&errStr,
+		&result.Settings.ReminderThresholds,
+		&result.Settings.Muted,
+		&result.Settings.Tags,
+		&result.Settings.SnoozedAt,
+		&result.Settings.SnoozedUntil,
+		&result.Settings.SnoozedFingerprint,
+		&result.Settings.AcknowledgedAt,
+		&result.Settings.AcknowledgedFingerprint,
+		&result.Settings.AcknowledgementNote,
 	)
 	if err != nil {
 		return Host{}, err
@@ -62,11 +93,13 @@ func (r *Repository) ByID(ctx context.Context, userID string, id int) (Host, err
 	return result, nil
 }
 
-func (r *Repository) ByName(ctx context.Context, userID, name string) (Host, error) {
+func (r *Repository) ByName(ctx context.Context, userID, name string, port int, protocol Protocol) (Host, error) {
 	row := r.db.QueryRow(ctx, `
 	SELECT
 		h.id,
 		h.hostname,
+		h.port,
+		h.protocol,
 		h.dns_names,
 		h.ip_address,
 		h.issued_by,
@@ -75,16 +108,35 @@ func (r *Repository) ByName(ctx context.Context, userID, name string) (Host, err
 		h.checked_at,
 		h.latency,
 		h.signature,
-		h.error_message
+		h.chain,
+		h.error_reason,
+		h.endpoints,
+		h.fingerprint_mismatch,
+		h.error_message,
+		uh.reminder_thresholds,
+		uh.muted,
+		uh.tags,
+		uh.snoozed_at,
+		uh.snoozed_until,
+		uh.snoozed_fingerprint,
+		uh.acknowledged_at,
+		uh.acknowledged_fingerprint,
+		uh.acknowledgement_note
 	FROM hosts h
 	INNER JOIN user_hosts uh
 		ON h.id = uh.host_id
-	WHERE h.hostname = $1 AND uh.user_id = $2`, name, userID)
+	WHERE
+		h.hostname = $1
+		AND h.port = $2
+		AND h.protocol = $3
+		AND uh.user_id = $4`, name, port, protocol, userID)
 	var result Host
 	var errStr *string
 	err := row.Scan(
 		&result.ID,
 		&result.Hostname,
+		&result.Port,
+		&result.Protocol,
 		&result.Certificate.DNSNames,
 		&result.Certificate.IP,
 		&result.Certificate.IssuedBy,
@@ -93,7 +145,20 @@ func (r *Repository) ByName(ctx context.Context, userID, name string) (Host, err
 		&result.Certificate.CheckedAt,
 		&result.Certificate.Latency,
 		&result.Certificate.Signature,
+		&result.Certificate.Chain,
+		&result.Certificate.Reason,
+		&result.Certificate.Endpoints,
+		&result.Certificate.FingerprintMismatch,
 		&errStr,
+		&result.Settings.ReminderThresholds,
+		&result.Settings.Muted,
+		&result.Settings.Tags,
+		&result.Settings.SnoozedAt,
+		&result.Settings.SnoozedUntil,
+		&result.Settings.SnoozedFingerprint,
+		&result.Settings.AcknowledgedAt,
+		&result.Settings.AcknowledgedFingerprint,
+		&result.Settings.AcknowledgementNote,
 	)
 	if err != nil {
 		return Host{}, err
@@ -116,6 +181,8 @@ func (r *Repository) All(ctx context.Context) ([]Host, error) {
 		SELECT
 			id,
 			hostname,
+			port,
+			protocol,
 			dns_names,
 			ip_address,
 			issued_by,
@@ -124,6 +191,10 @@ func (r *Repository) All(ctx context.Context) ([]Host, error) {
 			checked_at,
 			latency,
 			signature,
+			chain,
+			error_reason,
+			endpoints,
+			fingerprint_mismatch,
 			error_message
 		FROM hosts
 		ORDER BY
@@ -144,6 +215,8 @@ func (r *Repository) All(ctx context.Context) ([]Host, error) {
 		err := rows.Scan(
 			&h.ID,
 			&h.Hostname,
+			&h.Port,
+			&h.Protocol,
 			&h.Certificate.DNSNames,
 			&h.Certificate.IP,
 			&h.Certificate.IssuedBy,
@@ -152,6 +225,10 @@ func (r *Repository) All(ctx context.Context) ([]Host, error) {
 			&h.Certificate.CheckedAt,
 			&h.Certificate.Latency,
 			&h.Certificate.Signature,
+			&h.Certificate.Chain,
+			&h.Certificate.Reason,
+			&h.Certificate.Endpoints,
+			&h.Certificate.FingerprintMismatch,
 			&errStr,
 		)
 		if err != nil {
@@ -166,11 +243,32 @@ func (r *Repository) All(ctx context.Context) ([]Host, error) {
 	return hosts, nil
 }
 
-func (r *Repository) Expiring(ctx context.Context) ([]NotifiableHost, error) {
+// Expiring returns the hosts whose certificates have crossed one of the
+// reminder thresholds of the users tracking them. Only the latest threshold
+// crossed is returned, so that a host added close to its expiry doesn't
+// trigger a reminder for every earlier threshold.
+func (r *Repository) Expiring(ctx context.Context, notificationType int) ([]NotifiableHost, error) {
 	q := `
+	WITH reminders AS (
+		SELECT DISTINCT ON (uh.host_id, uh.user_id)
+			uh.host_id,
+			uh.user_id,
+			t.threshold
+		FROM user_hosts uh
+		INNER JOIN hosts h
+			ON h.id = uh.host_id
+		INNER JOIN settings s
+			ON s.user_id = uh.user_id
+		CROSS JOIN LATERAL unnest(COALESCE(uh.reminder_thresholds, s.reminder_thresholds)) AS t(threshold)
+		WHERE NOT uh.muted
+		AND (h.expires_at - (t.threshold * interval '1 second')) <= (now() at time zone 'utc')
+		ORDER BY uh.host_id, uh.user_id, t.threshold
+	)
 	SELECT
 		h.id,
 		h.hostname,
+		h.port,
+		h.protocol,
 		h.dns_names,
 		h.ip_address,
 		h.issued_by,
@@ -179,26 +277,30 @@ func (r *Repository) Expiring(ctx context.Context) ([]NotifiableHost, error) {
 		h.checked_at,
 		h.latency,
 		h.signature,
+		h.chain,
+		h.error_reason,
+		h.endpoints,
+		h.fingerprint_mismatch,
 		h.error_message,
 		u.id as user_id,
-		s.webhook_url,
-		s.reminder_threshold,
-		COALESCE(n.attempts, 0)
-	FROM hosts h
-	INNER JOIN user_hosts uh
-		ON h.id = uh.host_id
+		CASE WHEN s.email_verified_at IS NOT NULL THEN COALESCE(s.email, '') ELSE '' END,
+		s.time_zone,
+		rm.threshold
+	FROM reminders rm
+	INNER JOIN hosts h
+		ON h.id = rm.host_id
 	INNER JOIN users u
-		ON uh.user_id = u.id
+		ON rm.user_id = u.id
 	INNER JOIN settings s
 		ON u.id = s.user_id
 	LEFT JOIN notifications n
 		ON n.user_id = u.id
 		AND n.host_id = h.id
-		AND n.due = (h.expires_at - (s.reminder_threshold * interval '1 second'))
-	WHERE (h.expires_at - (s.reminder_threshold * interval '1 second')) <= (now() at time zone 'utc')
-	AND (n.id IS NULL OR (n.delivered_at IS NULL AND n.attempts < 3))
+		AND n.notification_type = $1
+		AND n.due = (h.expires_at - (rm.threshold * interval '1 second'))
+	WHERE n.id IS NULL
 	ORDER BY h.expires_at`
-	rows, err := r.db.Query(ctx, q)
+	rows, err := r.db.Query(ctx, q, notificationType)
 	if err != nil {
 		return nil, err
 	}
@@ -211,6 +313,8 @@ func (r *Repository) Expiring(ctx context.Context) ([]NotifiableHost, error) {
 		err := rows.Scan(
 			&record.Host.ID,
 			&record.Host.Hostname,
+			&record.Host.Port,
+			&record.Host.Protocol,
 			&record.Host.Certificate.DNSNames,
 			&record.Host.Certificate.IP,
 			&record.Host.Certificate.IssuedBy,
@@ -219,11 +323,309 @@ func (r *Repository) Expiring(ctx context.Context) ([]NotifiableHost, error) {
 			&record.Host.Certificate.CheckedAt,
 			&record.Host.Certificate.Latency,
 			&record.Host.Certificate.Signature,
+			&record.Host.Certificate.Chain,
+			&record.Host.Certificate.Reason,
+			&record.Host.Certificate.Endpoints,
+			&record.Host.Certificate.FingerprintMismatch,
 			&errStr,
 			&record.UserID,
-			&record.WebhookURL,
+			&record.Email,
+			&record.TimeZone,
 			&record.Threshold,
-			&record.Attempts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if errStr != nil {
+			record.Host.Certificate.Error = errors.New(*errStr)
+		}
+		hosts = append(hosts, record)
+	}
+
+	return hosts, nil
+}
+
+// Renewed returns the hosts whose latest certificate replaced an earlier one
+// during the past week, for every user who hasn't been notified about it.
+func (r *Repository) Renewed(ctx context.Context, notificationType int) ([]RenewedHost, error) {
+	q := `
+	SELECT
+		h.id,
+		h.hostname,
+		h.port,
+		h.protocol,
+		h.dns_names,
+		h.ip_address,
+		h.issued_by,
+		h.status,
+		h.expires_at,
+		h.checked_at,
+		h.latency,
+		h.signature,
+		h.chain,
+		h.error_reason,
+		h.endpoints,
+		h.fingerprint_mismatch,
+		h.error_message,
+		u.id as user_id,
+		CASE WHEN s.email_verified_at IS NOT NULL THEN COALESCE(s.email, '') ELSE '' END,
+		s.time_zone,
+		cur.fingerprint,
+		cur.subject,
+		cur.issuer,
+		cur.dns_names,
+		cur.not_before,
+		cur.not_after,
+		cur.first_seen,
+		cur.last_seen,
+		prev.fingerprint,
+		prev.subject,
+		prev.issuer,
+		prev.dns_names,
+		prev.not_before,
+		prev.not_after,
+		prev.first_seen,
+		prev.last_seen
+	FROM certificate_history cur
+	INNER JOIN LATERAL (
+		SELECT *
+		FROM certificate_history p
+		WHERE p.host_id = cur.host_id AND p.first_seen < cur.first_seen
+		ORDER BY p.first_seen DESC
+		LIMIT 1
+	) prev ON true
+	INNER JOIN hosts h
+		ON h.id = cur.host_id
+	INNER JOIN user_hosts uh
+		ON h.id = uh.host_id
+	INNER JOIN users u
+		ON uh.user_id = u.id
+	INNER JOIN settings s
+		ON u.id = s.user_id
+	LEFT JOIN notifications n
+		ON n.user_id = u.id
+		AND n.host_id = h.id
+		AND n.notification_type = $1
+		AND n.due = cur.first_seen
+	WHERE NOT uh.muted
+	AND cur.first_seen = (
+		SELECT max(first_seen) FROM certificate_history
+		WHERE host_id = cur.host_id
+	)
+	AND cur.first_seen > (now() at time zone 'utc') - interval '7 days'
+	AND n.id IS NULL
+	ORDER BY cur.first_seen`
+	rows, err := r.db.Query(ctx, q, notificationType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hosts []RenewedHost
+	for rows.Next() {
+		var record RenewedHost
+		var errStr *string
+		err := rows.Scan(
+			&record.Host.ID,
+			&record.Host.Hostname,
+			&record.Host.Port,
+			&record.Host.Protocol,
+			&record.Host.Certificate.DNSNames,
+			&record.Host.Certificate.IP,
+			&record.Host.Certificate.IssuedBy,
+			&record.Host.Certificate.Status,
+			&record.Host.Certificate.ExpiresAt,
+			&record.Host.Certificate.CheckedAt,
+			&record.Host.Certificate.Latency,
+			&record.Host.Certificate.Signature,
+			&record.Host.Certificate.Chain,
+			&record.Host.Certificate.Reason,
+			&record.Host.Certificate.Endpoints,
+			&record.Host.Certificate.FingerprintMismatch,
+			&errStr,
+			&record.UserID,
+			&record.Email,
+			&record.TimeZone,
+			&record.Current.Fingerprint,
+			&record.Current.Subject,
+			&record.Current.Issuer,
+			&record.Current.DNSNames,
+			&record.Current.NotBefore,
+			&record.Current.NotAfter,
+			&record.Current.FirstSeen,
+			&record.Current.LastSeen,
+			&record.Previous.Fingerprint,
+			&record.Previous.Subject,
+			&record.Previous.Issuer,
+			&record.Previous.DNSNames,
+			&record.Previous.NotBefore,
+			&record.Previous.NotAfter,
+			&record.Previous.FirstSeen,
+			&record.Previous.LastSeen,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if errStr != nil {
+			record.Host.Certificate.Error = errors.New(*errStr)
+		}
+		hosts = append(hosts, record)
+	}
+
+	return hosts, nil
+}
+
+// Failing returns the hosts that have failed at least as many checks in a row
+// as the users tracking them have configured, for every user tracking them.
+// notificationTypes are the types of the failure notifications, which the
+// user may already have been sent as listed in Notified.
+func (r *Repository) Failing(ctx context.Context, notificationTypes []int) ([]FailingHost, error) {
+	q := `
+	SELECT
+		h.id,
+		h.hostname,
+		h.port,
+		h.protocol,
+		h.dns_names,
+		h.ip_address,
+		h.issued_by,
+		h.status,
+		h.expires_at,
+		h.checked_at,
+		h.latency,
+		h.signature,
+		h.chain,
+		h.error_reason,
+		h.endpoints,
+		h.fingerprint_mismatch,
+		h.error_message,
+		u.id as user_id,
+		CASE WHEN s.email_verified_at IS NOT NULL THEN COALESCE(s.email, '') ELSE '' END,
+		s.time_zone,
+		h.failures,
+		h.failing_since,
+		h.recovered_at,
+		ARRAY(
+			SELECT f.notification_type FROM notifications f
+			WHERE f.user_id = u.id
+			AND f.host_id = h.id
+			AND f.notification_type = ANY($1)
+			AND f.due = h.failing_since
+		) AS notified
+	FROM hosts h
+	INNER JOIN user_hosts uh
+		ON h.id = uh.host_id
+	INNER JOIN users u
+		ON uh.user_id = u.id
+	INNER JOIN settings s
+		ON u.id = s.user_id
+	WHERE NOT uh.muted
+	AND h.failures >= s.failure_threshold
+	ORDER BY h.failing_since`
+	rows, err := r.db.Query(ctx, q, notificationTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFailingHosts(rows)
+}
+
+// Recovered returns the hosts that recovered during the past week, for every
+// user who was notified about the failure but not about the recovery.
+func (r *Repository) Recovered(ctx context.Context, notificationType int, failureTypes []int) ([]FailingHost, error) {
+	q := `
+	SELECT
+		h.id,
+		h.hostname,
+		h.port,
+		h.protocol,
+		h.dns_names,
+		h.ip_address,
+		h.issued_by,
+		h.status,
+		h.expires_at,
+		h.checked_at,
+		h.latency,
+		h.signature,
+		h.chain,
+		h.error_reason,
+		h.endpoints,
+		h.fingerprint_mismatch,
+		h.error_message,
+		u.id as user_id,
+		CASE WHEN s.email_verified_at IS NOT NULL THEN COALESCE(s.email, '') ELSE '' END,
+		s.time_zone,
+		h.failures,
+		h.failing_since,
+		h.recovered_at,
+		ARRAY(
+			SELECT f.notification_type FROM notifications f
+			WHERE f.user_id = u.id
+			AND f.host_id = h.id
+			AND f.notification_type = ANY($2)
+			AND f.due = h.failing_since
+		) AS notified
+	FROM hosts h
+	INNER JOIN user_hosts uh
+		ON h.id = uh.host_id
+	INNER JOIN users u
+		ON uh.user_id = u.id
+	INNER JOIN settings s
+		ON u.id = s.user_id
+	LEFT JOIN notifications n
+		ON n.user_id = u.id
+		AND n.host_id = h.id
+		AND n.notification_type = $1
+		AND n.due = h.recovered_at
+	WHERE NOT uh.muted
+	AND h.recovered_at > (now() at time zone 'utc') - interval '7 days'
+	AND EXISTS (
+		SELECT 1 FROM notifications f
+		WHERE f.user_id = u.id
+		AND f.host_id = h.id
+		AND f.notification_type = ANY($2)
+		AND f.due = h.failing_since
+	)
+	AND n.id IS NULL
+	ORDER BY h.recovered_at`
+	rows, err := r.db.Query(ctx, q, notificationType, failureTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanFailingHosts(rows)
+}
+
+func scanFailingHosts(rows pgx.Rows) ([]FailingHost, error) {
+	var hosts []FailingHost
+	for rows.Next() {
+		var record FailingHost
+		var errStr *string
+		err := rows.Scan(
+			&record.Host.ID,
+			&record.Host.Hostname,
+			&record.Host.Port,
+			&record.Host.Protocol,
+			&record.Host.Certificate.DNSNames,
+			&record.Host.Certificate.IP,
+			&record.Host.Certificate.IssuedBy,
+			&record.Host.Certificate.Status,
+			&record.Host.Certificate.ExpiresAt,
+			&record.Host.Certificate.CheckedAt,
+			&record.Host.Certificate.Latency,
+			&record.Host.Certificate.Signature,
+			&record.Host.Certificate.Chain,
+			&record.Host.Certificate.Reason,
+			&record.Host.Certificate.Endpoints,
+			&record.Host.Certificate.FingerprintMismatch,
+			&errStr,
+			&record.UserID,
+			&record.Email,
+			&record.TimeZone,
+			&record.Failures,
+			&record.FailingSince,
+			&record.RecoveredAt,
+			&record.Notified,
 		)
 		if err != nil {
 			return nil, err
@@ -248,6 +650,8 @@ func (r *Repository) AllByUser(ctx context.Context, userID string) ([]Host, erro
 		SELECT
 			h.id,
 			h.hostname,
+			h.port,
+			h.protocol,
 			h.dns_names,
 			h.ip_address,
 			h.issued_by,
@@ -256,7 +660,20 @@ func (r *Repository) AllByUser(ctx context.Context, userID string) ([]Host, erro
 			h.checked_at,
 			h.latency,
 			h.signature,
-			h.error_message
+			h.chain,
+			h.error_reason,
+			h.endpoints,
+			h.fingerprint_mismatch,
+			h.error_message,
+			uh.reminder_thresholds,
+			uh.muted,
+			uh.tags,
+			uh.snoozed_at,
+			uh.snoozed_until,
+			uh.snoozed_fingerprint,
+			uh.acknowledged_at,
+			uh.acknowledged_fingerprint,
+			uh.acknowledgement_note
 		FROM hosts h
 		INNER JOIN user_hosts uh
 			ON h.id = uh.host_id
@@ -264,7 +681,9 @@ func (r *Repository) AllByUser(ctx context.Context, userID string) ([]Host, erro
 		ORDER BY
 			array_position(%s, status),
 			expires_at,
-			hostname`,
+			hostname,
+			port,
+			protocol`,
 		order,
 	)
 	rows, err := r.db.Query(ctx, q, userID)
@@ -280,6 +699,8 @@ func (r *Repository) AllByUser(ctx context.Context, userID string) ([]Host, erro
 		err := rows.Scan(
 			&h.ID,
 			&h.Hostname,
+			&h.Port,
+			&h.Protocol,
 			&h.Certificate.DNSNames,
 			&h.Certificate.IP,
 			&h.Certificate.IssuedBy,
@@ -288,7 +709,20 @@ func (r *Repository) AllByUser(ctx context.Context, userID string) ([]Host, erro
 			&h.Certificate.CheckedAt,
 			&h.Certificate.Latency,
 			&h.Certificate.Signature,
+			&h.Certificate.Chain,
+			&h.Certificate.Reason,
+			&h.Certificate.Endpoints,
+			&h.Certificate.FingerprintMismatch,
 			&errStr,
+			&h.Settings.ReminderThresholds,
+			&h.Settings.Muted,
+			&h.Settings.Tags,
+			&h.Settings.SnoozedAt,
+			&h.Settings.SnoozedUntil,
+			&h.Settings.SnoozedFingerprint,
+			&h.Settings.AcknowledgedAt,
+			&h.Settings.AcknowledgedFingerprint,
+			&h.Settings.AcknowledgementNote,
 		)
 		if err != nil {
 			return nil, err
@@ -302,6 +736,190 @@ func (r *Repository) AllByUser(ctx context.Context, userID string) ([]Host, erro
 	return hosts, nil
 }
 
+// List returns a page of the hosts of a user matching the options.
+func (r *Repository) List(ctx context.Context, userID string, opts ListOptions) (HostList, error) {
+	sort := opts.Sort
+	if sort == "" {
+		sort = "status"
+	}
+	keys, desc, err := listSort(sort)
+	if err != nil {
+		return HostList{}, err
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+
+	args := []any{userID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	where := []string{"uh.user_id = $1"}
+	if len(opts.Statuses) > 0 {
+		statuses := make([]int, len(opts.Statuses))
+		for i, s := range opts.Statuses {
+			statuses[i] = int(s)
+		}
+		where = append(where, fmt.Sprintf("h.status = ANY(%s)", arg(statuses)))
+	}
+	if opts.ExpiresBefore != nil {
+		where = append(where, fmt.Sprintf("h.expires_at < %s", arg(opts.ExpiresBefore.UTC())))
+	}
+	if opts.Issuer != "" {
+		where = append(where, fmt.Sprintf("lower(h.issued_by) = lower(%s)", arg(opts.Issuer)))
+	}
+	if opts.Hostname != "" {
+		where = append(where, fmt.Sprintf("h.hostname ILIKE %s", arg("%"+escapeLike(opts.Hostname)+"%")))
+	}
+	if opts.Tag != "" {
+		where = append(where, fmt.Sprintf("%s = ANY(uh.tags)", arg(opts.Tag)))
+	}
+
+	var result HostList
+	err = r.db.QueryRow(ctx, fmt.Sprintf(`
+		SELECT count(*)
+		FROM hosts h
+		INNER JOIN user_hosts uh
+			ON h.id = uh.host_id
+		WHERE %s`,
+		strings.Join(where, " AND "),
+	), args...).Scan(&result.Total)
+	if err != nil {
+		return HostList{}, err
+	}
+
+	exprs := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		exprs = append(exprs, k.expr)
+	}
+	exprs = append(exprs, "h.id")
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+	if opts.Cursor != "" {
+		values, err := decodeCursor(opts.Cursor, sort, keys)
+		if err != nil {
+			return HostList{}, err
+		}
+		params := make([]string, len(values))
+		for i, v := range values {
+			kind := keyInt
+			if i < len(keys) {
+				kind = keys[i].kind
+			}
+			params[i] = arg(v) + "::" + kind.cast()
+		}
+		where = append(where, fmt.Sprintf(
+			"(%s) %s (%s)",
+			strings.Join(exprs, ", "),
+			cmp,
+			strings.Join(params, ", "),
+		))
+	}
+	order := make([]string, len(exprs))
+	for i, e := range exprs {
+		order[i] = e + " " + dir
+	}
+
+	q := fmt.Sprintf(`
+		SELECT
+			h.id,
+			h.hostname,
+			h.port,
+			h.protocol,
+			h.dns_names,
+			h.ip_address,
+			h.issued_by,
+			h.status,
+			h.expires_at,
+			h.checked_at,
+			h.latency,
+			h.signature,
+			h.chain,
+			h.error_reason,
+			h.endpoints,
+			h.fingerprint_mismatch,
+			h.error_message,
+			uh.reminder_thresholds,
+			uh.muted,
+			uh.tags,
+			uh.snoozed_at,
+			uh.snoozed_until,
+			uh.snoozed_fingerprint,
+			uh.acknowledged_at,
+			uh.acknowledged_fingerprint,
+			uh.acknowledgement_note
+		FROM hosts h
+		INNER JOIN user_hosts uh
+			ON h.id = uh.host_id
+		WHERE %s
+		ORDER BY %s
+		LIMIT %s`,
+		strings.Join(where, " AND "),
+		strings.Join(order, ", "),
+		arg(limit+1),
+	)
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return HostList{}, err
+	}
+	defer rows.Close()
+
+	result.Hosts = []Host{}
+	for rows.Next() {
+		var h Host
+		var errStr *string
+		err := rows.Scan(
+			&h.ID,
+			&h.Hostname,
+			&h.Port,
+			&h.Protocol,
+			&h.Certificate.DNSNames,
+			&h.Certificate.IP,
+			&h.Certificate.IssuedBy,
+			&h.Certificate.Status,
+			&h.Certificate.ExpiresAt,
+			&h.Certificate.CheckedAt,
+			&h.Certificate.Latency,
+			&h.Certificate.Signature,
+			&h.Certificate.Chain,
+			&h.Certificate.Reason,
+			&h.Certificate.Endpoints,
+			&h.Certificate.FingerprintMismatch,
+			&errStr,
+			&h.Settings.ReminderThresholds,
+			&h.Settings.Muted,
+			&h.Settings.Tags,
+			&h.Settings.SnoozedAt,
+			&h.Settings.SnoozedUntil,
+			&h.Settings.SnoozedFingerprint,
+			&h.Settings.AcknowledgedAt,
+			&h.Settings.AcknowledgedFingerprint,
+			&h.Settings.AcknowledgementNote,
+		)
+		if err != nil {
+			return HostList{}, err
+		}
+		if errStr != nil {
+			h.Certificate.Error = errors.New(*errStr)
+		}
+		result.Hosts = append(result.Hosts, h)
+	}
+	if err := rows.Err(); err != nil {
+		return HostList{}, err
+	}
+
+	if len(result.Hosts) > limit {
+		result.Hosts = result.Hosts[:limit]
+		result.NextCursor = encodeCursor(sort, keys, result.Hosts[limit-1])
+	}
+	return result, nil
+}
+
 func (r *Repository) Create(ctx context.Context, uid string, hosts []Host) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
@@ -320,9 +938,17 @@ func (r *Repository) Create(ctx context.Context, uid string, hosts []Host) error
 			str := h.Certificate.Error.Error()
 			errStr = &str
 		}
+		var failures int
+		var failingSince *time.Time
+		if h.Certificate.Failing() {
+			failures = 1
+			failingSince = &h.Certificate.CheckedAt
+		}
 		err := tx.QueryRow(ctx, `
 		INSERT INTO hosts (
 			hostname,
+			port,
+			protocol,
 			dns_names,
 			ip_address,
 			issued_by,
@@ -331,22 +957,34 @@ func (r *Repository) Create(ctx context.Context, uid string, hosts []Host) error
 			checked_at,
 			latency,
 			signature,
-			error_message
+			chain,
+			error_reason,
+			endpoints,
+			fingerprint_mismatch,
+			error_message,
+			failures,
+			failing_since
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		ON CONFLICT (hostname) DO UPDATE SET
-			dns_names      = EXCLUDED.dns_names,
-			ip_address     = EXCLUDED.ip_address,
-			issued_by      = EXCLUDED.issued_by,
-			status         = EXCLUDED.status,
-			expires_at     = EXCLUDED.expires_at,
-			checked_at     = EXCLUDED.checked_at,
-			latency        = EXCLUDED.latency,
-			signature      = EXCLUDED.signature,
-			error_message  = EXCLUDED.error_message
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE($12, '[]'::jsonb), $13, COALESCE($14, '[]'::jsonb), $15, $16, $17, $18)
+		ON CONFLICT (hostname, port, protocol) DO UPDATE SET
+			dns_names            = EXCLUDED.dns_names,
+			ip_address           = EXCLUDED.ip_address,
+			issued_by            = EXCLUDED.issued_by,
+			status               = EXCLUDED.status,
+			expires_at           = EXCLUDED.expires_at,
+			checked_at           = EXCLUDED.checked_at,
+			latency              = EXCLUDED.latency,
+			signature            = EXCLUDED.signature,
+			chain                = EXCLUDED.chain,
+			error_reason         = EXCLUDED.error_reason,
+			endpoints            = EXCLUDED.endpoints,
+			fingerprint_mismatch = EXCLUDED.fingerprint_mismatch,
+			error_message        = EXCLUDED.error_message
 		RETURNING id
 		`,
 			h.Hostname,
+			h.Port,
+			h.Protocol,
 			h.Certificate.DNSNames,
 			h.Certificate.IP,
 			h.Certificate.IssuedBy,
@@ -355,11 +993,20 @@ func (r *Repository) Create(ctx context.Context, uid string, hosts []Host) error
 			h.Certificate.CheckedAt,
 			h.Certificate.Latency,
 			h.Certificate.Signature,
+			h.Certificate.Chain,
+			h.Certificate.Reason,
+			h.Certificate.Endpoints,
+			h.Certificate.FingerprintMismatch,
 			errStr,
+			failures,
+			failingSince,
 		).Scan(&id)
 		if err != nil {
 			return err
 		}
+		if err := recordHistory(ctx, tx, id, h.Certificate); err != nil {
+			return err
+		}
 
 		_, err = tx.Exec(ctx,
 			`INSERT INTO user_hosts (host_id, user_id) VALUES ($1, $2)`,
@@ -368,7 +1015,7 @@ func (r *Repository) Create(ctx context.Context, uid string, hosts []Host) error
 		if err != nil {
 			str := `duplicate key value violates unique constraint "uq_user_hosts_user_id_host_id"`
 			if strings.Contains(err.Error(), str) {
-				return fmt.Errorf("already tracking %s", h.Hostname)
+				return fmt.Errorf("%w %s", ErrAlreadyTracking, h.Address())
 			}
 			return err
 		}
@@ -379,6 +1026,101 @@ func (r *Repository) Create(ctx context.Context, uid string, hosts []Host) error
 	return nil
 }
 
+// UpdateSettings saves the settings of a host for a user. The tags are kept
+// if settings.Tags is nil.
+func (r *Repository) UpdateSettings(ctx context.Context, userID string, hostID int, settings HostSettings) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE user_hosts
+		SET
+			reminder_thresholds = $3,
+			muted = $4,
+			tags = COALESCE($5, tags)
+		WHERE host_id = $1 AND user_id = $2`,
+		hostID,
+		userID,
+		settings.ReminderThresholds,
+		settings.Muted,
+		settings.Tags,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Snooze snoozes the notifications about a host for a user until the time,
+// or while the host serves its current certificate. A nil until ends the
+// snooze.
+func (r *Repository) Snooze(ctx context.Context, userID string, hostID int, until *time.Time) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE user_hosts uh
+		SET
+			snoozed_at = CASE WHEN $3::timestamp IS NULL THEN NULL ELSE (now() at time zone 'utc') END,
+			snoozed_until = $3,
+			snoozed_fingerprint = CASE WHEN $3::timestamp IS NULL THEN '' ELSE h.signature END
+		FROM hosts h
+		WHERE h.id = uh.host_id AND uh.host_id = $1 AND uh.user_id = $2`,
+		hostID,
+		userID,
+		until,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Acknowledge acknowledges the notifications about a host that are due for a
+// user, with an optional note.
+func (r *Repository) Acknowledge(ctx context.Context, userID string, hostID int, note string) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE user_hosts uh
+		SET
+			acknowledged_at = (now() at time zone 'utc'),
+			acknowledged_fingerprint = h.signature,
+			acknowledgement_note = $3
+		FROM hosts h
+		WHERE h.id = uh.host_id AND uh.host_id = $1 AND uh.user_id = $2`,
+		hostID,
+		userID,
+		note,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Unacknowledge removes the acknowledgement of a host for a user.
+func (r *Repository) Unacknowledge(ctx context.Context, userID string, hostID int) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE user_hosts
+		SET
+			acknowledged_at = NULL,
+			acknowledged_fingerprint = '',
+			acknowledgement_note = ''
+		WHERE host_id = $1 AND user_id = $2`,
+		hostID,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
 func (r *Repository) Delete(ctx context.Context, uid string, id int) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
@@ -437,9 +1179,16 @@ func (r *Repository) Update(ctx context.Context, hosts []Host) error {
 			checked_at = $6,
 			latency = $7,
 			signature = $8,
-			error_message = $9,
+			chain = COALESCE($9, '[]'::jsonb),
+			error_reason = $10,
+			endpoints = COALESCE($11, '[]'::jsonb),
+			fingerprint_mismatch = $12,
+			error_message = $13,
+			failures = CASE WHEN $15 THEN failures + 1 ELSE 0 END,
+			failing_since = CASE WHEN $15 AND failures = 0 THEN $6 ELSE failing_since END,
+			recovered_at = CASE WHEN $15 THEN NULL WHEN failures > 0 THEN $6 ELSE recovered_at END,
 			updated_at = (now() at time zone 'utc')
-		WHERE id = $10
+		WHERE id = $14
 		`,
 			h.Certificate.DNSNames,
 			h.Certificate.IP,
@@ -449,12 +1198,20 @@ func (r *Repository) Update(ctx context.Context, hosts []Host) error {
 			h.Certificate.CheckedAt,
 			h.Certificate.Latency,
 			h.Certificate.Signature,
+			h.Certificate.Chain,
+			h.Certificate.Reason,
+			h.Certificate.Endpoints,
+			h.Certificate.FingerprintMismatch,
 			errStr,
 			h.ID,
+			h.Certificate.Failing(),
 		)
 		if err != nil {
 			return err
 		}
+		if err := recordHistory(ctx, tx, h.ID, h.Certificate); err != nil {
+			return err
+		}
 	}
 	if err := tx.Commit(ctx); err != nil {
 		return err
@@ -462,3 +1219,82 @@ func (r *Repository) Update(ctx context.Context, hosts []Host) error {
 
 	return nil
 }
+
+// recordHistory adds the leaf certificate of cert to the history of the host,
+// or updates when it was last seen if it's already there.
+func recordHistory(ctx context.Context, tx pgx.Tx, hostID int, cert CertificateInfo) error {
+	if cert.Signature == "" || len(cert.Chain) == 0 {
+		return nil
+	}
+	leaf := cert.Chain[0]
+	_, err := tx.Exec(ctx, `
+		INSERT INTO certificate_history (
+			host_id,
+			fingerprint,
+			subject,
+			issuer,
+			dns_names,
+			not_before,
+			not_after,
+			first_seen,
+			last_seen
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (host_id, fingerprint) DO UPDATE SET
+			last_seen = GREATEST(certificate_history.last_seen, EXCLUDED.last_seen)
+		`,
+		hostID,
+		cert.Signature,
+		leaf.Subject,
+		cert.IssuedBy,
+		cert.DNSNames,
+		leaf.NotBefore,
+		leaf.NotAfter,
+		cert.CheckedAt,
+	)
+	return err
+}
+
+// History returns the certificates seen on a host, most recent first.
+func (r *Repository) History(ctx context.Context, userID string, hostID int) ([]HistoryEntry, error) {
+	rows, err := r.db.Query(ctx, `
+	SELECT
+		ch.fingerprint,
+		ch.subject,
+		ch.issuer,
+		ch.dns_names,
+		ch.not_before,
+		ch.not_after,
+		ch.first_seen,
+		ch.last_seen
+	FROM certificate_history ch
+	INNER JOIN user_hosts uh
+		ON ch.host_id = uh.host_id
+	WHERE ch.host_id = $1 AND uh.user_id = $2
+	ORDER BY ch.first_seen DESC, ch.last_seen DESC`, hostID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []HistoryEntry
+	for rows.Next() {
+		var e HistoryEntry
+		err := rows.Scan(
+			&e.Fingerprint,
+			&e.Subject,
+			&e.Issuer,
+			&e.DNSNames,
+			&e.NotBefore,
+			&e.NotAfter,
+			&e.FirstSeen,
+			&e.LastSeen,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, e)
+	}
+
+	return history, nil
+}
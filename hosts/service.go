@@ -4,66 +4,91 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	// CheckBurst is how many hosts a user can check on demand at once.
+	CheckBurst = 5
+	// CheckInterval is how often a user can check a host on demand after
+	// using up CheckBurst.
+	CheckInterval = time.Minute
+)
+
 type Service struct {
-	repo *Repository
+	repo   *Repository
+	checks *limiter
 }
 
 func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+	return &Service{
+		repo:   repo,
+		checks: newLimiter(CheckBurst, CheckInterval),
+	}
 }
 
 func (s *Service) ByID(ctx context.Context, id int, userID string) (Host, error) {
 	return s.repo.ByID(ctx, userID, id)
 }
 
-func (s *Service) ByName(ctx context.Context, name, userID string) (Host, error) {
-	return s.repo.ByName(ctx, userID, name)
+func (s *Service) ByName(ctx context.Context, target Host, userID string) (Host, error) {
+	return s.repo.ByName(ctx, userID, target.Hostname, target.Port, target.Protocol)
+}
+
+func (s *Service) History(ctx context.Context, id int, userID string) ([]HistoryEntry, error) {
+	return s.repo.History(ctx, userID, id)
 }
 
 func (s *Service) AllByUser(ctx context.Context, userID string) ([]Host, error) {
 	return s.repo.AllByUser(ctx, userID)
 }
 
+// List returns a page of the hosts of a user matching the options.
+func (s *Service) List(ctx context.Context, userID string, opts ListOptions) (HostList, error) {
+	return s.repo.List(ctx, userID, opts)
+}
+
 func (s *Service) All(ctx context.Context) ([]Host, error) {
 	return s.repo.All(ctx)
 }
 
-func (s *Service) Expiring(ctx context.Context) ([]NotifiableHost, error) {
+func (s *Service) Expiring(ctx context.Context, notificationType int) ([]NotifiableHost, error) {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
-	return s.repo.Expiring(ctx)
+	return s.repo.Expiring(ctx, notificationType)
 }
 
-func (s *Service) Create(uid string, names []string) error {
-	hostch := make(chan Host, len(names))
+func (s *Service) Renewed(ctx context.Context, notificationType int) ([]RenewedHost, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	return s.repo.Renewed(ctx, notificationType)
+}
+
+func (s *Service) Failing(ctx context.Context, notificationTypes []int) ([]FailingHost, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	return s.repo.Failing(ctx, notificationTypes)
+}
+
+func (s *Service) Recovered(ctx context.Context, notificationType int, failureTypes []int) ([]FailingHost, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	return s.repo.Recovered(ctx, notificationType, failureTypes)
+}
+
+// Create fetches the certificates for the given hosts and starts tracking
+// them. Only the Hostname, Port and Protocol of each input host are used.
+func (s *Service) Create(uid string, input []Host) error {
+	hostch := make(chan Host, len(input))
 	hosts := make([]Host, 0)
 	eg, ctx := errgroup.WithContext(context.Background())
-	for _, name := range names {
+	for _, in := range input {
 		eg.Go(func() error {
-			info, err := FetchCert(context.Background(), name)
+			host, err := fetch(in)
 			if err != nil {
-				if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "Temporary failure in name resolution") {
-					return fmt.Errorf("can't connect to %s", name)
-				}
-				if !errors.Is(err, context.DeadlineExceeded) {
-					return fmt.Errorf("fetch cert: %v", err)
-				}
-				info = &CertificateInfo{
-					Status:    CertificateStatusOffline,
-					IssuedBy:  "n/a",
-					CheckedAt: time.Now().UTC(),
-					Error:     err,
-				}
-			}
-			host := Host{
-				Hostname:    name,
-				Certificate: *info,
+				return err
 			}
 			select {
 			case hostch <- host:
@@ -86,10 +111,81 @@ func (s *Service) Create(uid string, names []string) error {
 	return s.repo.Create(ctx, uid, hosts)
 }
 
+// fetch checks the certificate of a host to be created. Hosts that time out
+// are created as offline.
+func fetch(in Host) (Host, error) {
+	if in.Protocol == "" {
+		in.Protocol = ProtocolTLS
+	}
+	info, err := FetchCert(context.Background(), in.Hostname, in.Port, in.Protocol)
+	if err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return Host{}, fmt.Errorf("fetch cert: %v", err)
+		}
+		info = &CertificateInfo{
+			Status:    CertificateStatusOffline,
+			IssuedBy:  "n/a",
+			CheckedAt: time.Now().UTC(),
+			Error:     err,
+		}
+	}
+	return Host{
+		Hostname:    in.Hostname,
+		Port:        in.Port,
+		Protocol:    in.Protocol,
+		Certificate: *info,
+	}, nil
+}
+
 func (s *Service) Update(ctx context.Context, hosts []Host) error {
 	return s.repo.Update(ctx, hosts)
 }
 
+// Check fetches the certificate of a host right away and saves the result.
+// It returns a *RateLimitError if the user checks hosts too often.
+func (s *Service) Check(userID string, id int) (Host, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	host, err := s.repo.ByID(ctx, userID, id)
+	if err != nil {
+		return Host{}, err
+	}
+	if ok, wait := s.checks.allow(userID, time.Now()); !ok {
+		return Host{}, &RateLimitError{RetryAfter: wait}
+	}
+	host = check(host)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := s.repo.Update(ctx, []Host{host}); err != nil {
+		return Host{}, err
+	}
+	return s.repo.ByID(ctx, userID, id)
+}
+
+func (s *Service) UpdateSettings(userID string, id int, settings HostSettings) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	return s.repo.UpdateSettings(ctx, userID, id, settings)
+}
+
+func (s *Service) Snooze(userID string, id int, until *time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	return s.repo.Snooze(ctx, userID, id, until)
+}
+
+func (s *Service) Acknowledge(userID string, id int, note string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	return s.repo.Acknowledge(ctx, userID, id, note)
+}
+
+func (s *Service) Unacknowledge(userID string, id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	return s.repo.Unacknowledge(ctx, userID, id)
+}
+
 func (s *Service) Delete(userID string, id int) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
@@ -0,0 +1,267 @@
+package hosts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+)
+
+// Protocol determines how the TLS session is negotiated with a host. All
+// protocols except ProtocolTLS start in plaintext and upgrade the connection
+// to TLS before the certificate is read.
+type Protocol string
+
+const (
+	ProtocolTLS      Protocol = "tls"
+	ProtocolSMTP     Protocol = "smtp"
+	ProtocolIMAP     Protocol = "imap"
+	ProtocolPOP3     Protocol = "pop3"
+	ProtocolXMPP     Protocol = "xmpp"
+	ProtocolPostgres Protocol = "postgres"
+)
+
+var Protocols = []Protocol{
+	ProtocolTLS,
+	ProtocolSMTP,
+	ProtocolIMAP,
+	ProtocolPOP3,
+	ProtocolXMPP,
+	ProtocolPostgres,
+}
+
+func NewProtocol(input string) (Protocol, bool) {
+	for _, p := range Protocols {
+		if string(p) == strings.ToLower(strings.TrimSpace(input)) {
+			return p, true
+		}
+	}
+	return Protocol(""), false
+}
+
+// ProtocolForPort returns the protocol commonly served on the given port.
+func ProtocolForPort(port int) Protocol {
+	switch port {
+	case 25, 587:
+		return ProtocolSMTP
+	case 143:
+		return ProtocolIMAP
+	case 110:
+		return ProtocolPOP3
+	case 5222:
+		return ProtocolXMPP
+	case 5432:
+		return ProtocolPostgres
+	default:
+		return ProtocolTLS
+	}
+}
+
+func (p Protocol) String() string {
+	if p == "" {
+		return string(ProtocolTLS)
+	}
+	return string(p)
+}
+
+func (p Protocol) DefaultPort() int {
+	switch p {
+	case ProtocolSMTP:
+		return 587
+	case ProtocolIMAP:
+		return 143
+	case ProtocolPOP3:
+		return 110
+	case ProtocolXMPP:
+		return 5222
+	case ProtocolPostgres:
+		return 5432
+	default:
+		return DefaultPort
+	}
+}
+
+// Label returns a human readable name for the protocol.
+func (p Protocol) Label() string {
+	switch p {
+	case ProtocolSMTP:
+		return "SMTP (STARTTLS)"
+	case ProtocolIMAP:
+		return "IMAP (STARTTLS)"
+	case ProtocolPOP3:
+		return "POP3 (STLS)"
+	case ProtocolXMPP:
+		return "XMPP (STARTTLS)"
+	case ProtocolPostgres:
+		return "PostgreSQL"
+	default:
+		return "TLS"
+	}
+}
+
+// startTLS performs the plaintext part of the protocol handshake, leaving conn
+// ready for a TLS client handshake.
+func startTLS(conn net.Conn, hostname string, p Protocol) error {
+	var err error
+	switch p {
+	case "", ProtocolTLS:
+		return nil
+	case ProtocolSMTP:
+		err = startSMTP(conn)
+	case ProtocolIMAP:
+		err = startIMAP(conn)
+	case ProtocolPOP3:
+		err = startPOP3(conn)
+	case ProtocolXMPP:
+		err = startXMPP(conn, hostname)
+	case ProtocolPostgres:
+		err = startPostgres(conn)
+	default:
+		return fmt.Errorf("unsupported protocol: %s", p)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStartTLS, err)
+	}
+	return nil
+}
+
+func startSMTP(conn net.Conn) error {
+	tp := textproto.NewConn(conn)
+	if _, _, err := tp.ReadResponse(220); err != nil {
+		return err
+	}
+	if err := tp.PrintfLine("EHLO neverexpire"); err != nil {
+		return err
+	}
+	_, msg, err := tp.ReadResponse(250)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(strings.ToUpper(msg), "STARTTLS") {
+		return fmt.Errorf("server doesn't support STARTTLS")
+	}
+	if err := tp.PrintfLine("STARTTLS"); err != nil {
+		return err
+	}
+	_, _, err = tp.ReadResponse(220)
+	return err
+}
+
+func startIMAP(conn net.Conn) error {
+	tp := textproto.NewConn(conn)
+	greeting, err := tp.ReadLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("unexpected greeting: %s", greeting)
+	}
+	if err := tp.PrintfLine("a001 STARTTLS"); err != nil {
+		return err
+	}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "a001 ") {
+			continue
+		}
+		if !strings.HasPrefix(line, "a001 OK") {
+			return fmt.Errorf("unexpected response: %s", line)
+		}
+		return nil
+	}
+}
+
+func startPOP3(conn net.Conn) error {
+	tp := textproto.NewConn(conn)
+	greeting, err := tp.ReadLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return fmt.Errorf("unexpected greeting: %s", greeting)
+	}
+	if err := tp.PrintfLine("STLS"); err != nil {
+		return err
+	}
+	line, err := tp.ReadLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("unexpected response: %s", line)
+	}
+	return nil
+}
+
+func startXMPP(conn net.Conn, hostname string) error {
+	header := fmt.Sprintf(
+		"<?xml version='1.0'?><stream:stream to='%s' version='1.0' xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams'>",
+		hostname,
+	)
+	if _, err := io.WriteString(conn, header); err != nil {
+		return err
+	}
+	features, err := readUntil(conn, "</stream:features>")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(features, "<starttls") {
+		return fmt.Errorf("server doesn't support STARTTLS")
+	}
+	if _, err := io.WriteString(conn, "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"); err != nil {
+		return err
+	}
+	res, err := readUntil(conn, "<proceed", "<failure")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(res, "<proceed") {
+		return fmt.Errorf("server refused STARTTLS")
+	}
+	return nil
+}
+
+func startPostgres(conn net.Conn) error {
+	// SSLRequest: message length followed by the SSL request code
+	req := make([]byte, 8)
+	binary.BigEndian.PutUint32(req[0:4], 8)
+	binary.BigEndian.PutUint32(req[4:8], 80877103)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	res := make([]byte, 1)
+	if _, err := io.ReadFull(conn, res); err != nil {
+		return err
+	}
+	if res[0] != 'S' {
+		return fmt.Errorf("server doesn't support SSL")
+	}
+	return nil
+}
+
+// readUntil reads from r until the data read contains one of the markers.
+// It reads directly from the connection so that no bytes belonging to the
+// TLS handshake are consumed.
+func readUntil(r io.Reader, markers ...string) (string, error) {
+	var buf bytes.Buffer
+	chunk := make([]byte, 1024)
+	for buf.Len() < 64*1024 {
+		n, err := r.Read(chunk)
+		buf.Write(chunk[:n])
+		for _, m := range markers {
+			if strings.Contains(buf.String(), m) {
+				return buf.String(), nil
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("response too long")
+}
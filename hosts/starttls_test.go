@@ -0,0 +1,220 @@
+package hosts
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartTLS(t *testing.T) {
+	cert, err := newTestCertificate("localhost")
+	if err != nil {
+		t.Fatalf("failed to create test certificate: %v", err)
+	}
+	tests := []struct {
+		protocol Protocol
+		server   func(conn net.Conn) error
+	}{
+		{protocol: ProtocolTLS, server: func(conn net.Conn) error { return nil }},
+		{protocol: ProtocolSMTP, server: fakeSMTP},
+		{protocol: ProtocolIMAP, server: fakeIMAP},
+		{protocol: ProtocolPOP3, server: fakePOP3},
+		{protocol: ProtocolXMPP, server: fakeXMPP},
+		{protocol: ProtocolPostgres, server: fakePostgres},
+	}
+	for _, ts := range tests {
+		t.Run(ts.protocol.String(), func(t *testing.T) {
+			addr := serveOnce(t, cert, ts.server)
+			conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
+			if err != nil {
+				t.Fatalf("failed to connect: %v", err)
+			}
+			defer conn.Close()
+			if err := conn.SetDeadline(time.Now().Add(2 * time.Second)); err != nil {
+				t.Fatalf("failed to set deadline: %v", err)
+			}
+			if err := startTLS(conn, "localhost", ts.protocol); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tlsConn := tls.Client(conn, &tls.Config{
+				ServerName:         "localhost",
+				InsecureSkipVerify: true,
+			})
+			if err := tlsConn.Handshake(); err != nil {
+				t.Fatalf("tls handshake failed: %v", err)
+			}
+			peer := tlsConn.ConnectionState().PeerCertificates
+			if len(peer) == 0 || !bytes.Equal(peer[0].Raw, cert.Certificate[0]) {
+				t.Errorf("peer certificate doesn't match the server certificate")
+			}
+		})
+	}
+}
+
+func TestStartTLSRefused(t *testing.T) {
+	cert, err := newTestCertificate("localhost")
+	if err != nil {
+		t.Fatalf("failed to create test certificate: %v", err)
+	}
+	addr := serveOnce(t, cert, func(conn net.Conn) error {
+		if _, err := io.ReadFull(conn, make([]byte, 8)); err != nil {
+			return err
+		}
+		_, err := conn.Write([]byte{'N'})
+		return err
+	})
+	conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	err = startTLS(conn, "localhost", ProtocolPostgres)
+	if err == nil {
+		t.Fatal("expected error and got none")
+	}
+	if mapError(err) != ErrStartTLS {
+		t.Errorf("expected %v, got %v", ErrStartTLS, mapError(err))
+	}
+}
+
+// serveOnce accepts a single connection, runs the plaintext part of the
+// protocol and then completes a TLS handshake as the server.
+func serveOnce(t *testing.T, cert tls.Certificate, plaintext func(net.Conn) error) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if err := conn.SetDeadline(time.Now().Add(2 * time.Second)); err != nil {
+			return
+		}
+		if err := plaintext(conn); err != nil {
+			return
+		}
+		srv := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+		_ = srv.Handshake()
+	}()
+	return l.Addr().String()
+}
+
+func fakeSMTP(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	if _, err := io.WriteString(conn, "220 localhost ESMTP ready\r\n"); err != nil {
+		return err
+	}
+	if err := expectLine(r, "EHLO"); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(conn, "250-localhost\r\n250-PIPELINING\r\n250 STARTTLS\r\n"); err != nil {
+		return err
+	}
+	if err := expectLine(r, "STARTTLS"); err != nil {
+		return err
+	}
+	_, err := io.WriteString(conn, "220 Ready to start TLS\r\n")
+	return err
+}
+
+func fakeIMAP(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	if _, err := io.WriteString(conn, "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n"); err != nil {
+		return err
+	}
+	if err := expectLine(r, "a001 STARTTLS"); err != nil {
+		return err
+	}
+	_, err := io.WriteString(conn, "a001 OK Begin TLS negotiation now\r\n")
+	return err
+}
+
+func fakePOP3(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	if _, err := io.WriteString(conn, "+OK POP3 server ready\r\n"); err != nil {
+		return err
+	}
+	if err := expectLine(r, "STLS"); err != nil {
+		return err
+	}
+	_, err := io.WriteString(conn, "+OK Begin TLS negotiation\r\n")
+	return err
+}
+
+func fakeXMPP(conn net.Conn) error {
+	if _, err := readUntil(conn, "<stream:stream"); err != nil {
+		return err
+	}
+	features := "<?xml version='1.0'?><stream:stream from='localhost' version='1.0' xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams'>" +
+		"<stream:features><starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'><required/></starttls></stream:features>"
+	if _, err := io.WriteString(conn, features); err != nil {
+		return err
+	}
+	if _, err := readUntil(conn, "<starttls"); err != nil {
+		return err
+	}
+	_, err := io.WriteString(conn, "<proceed xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>")
+	return err
+}
+
+func fakePostgres(conn net.Conn) error {
+	req := make([]byte, 8)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return err
+	}
+	if code := binary.BigEndian.Uint32(req[4:8]); code != 80877103 {
+		return fmt.Errorf("unexpected request code: %d", code)
+	}
+	_, err := conn.Write([]byte{'S'})
+	return err
+}
+
+func expectLine(r *bufio.Reader, prefix string) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, prefix) {
+		return fmt.Errorf("expected %s, got %s", prefix, line)
+	}
+	return nil
+}
+
+func newTestCertificate(name string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"neverexpire test"}},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
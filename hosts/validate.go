@@ -4,21 +4,70 @@ import (
 	"fmt"
 	"net/url"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// ParseHostname parses the hostname from user input, ignoring any port.
 func ParseHostname(input string) (string, error) {
+	name, _, err := ParseHost(input)
+	return name, err
+}
+
+// ParseHost parses the hostname and port from user input. The port defaults
+// to DefaultPort if the input doesn't specify one.
+func ParseHost(input string) (string, int, error) {
+	name, port, err := parseHost(input)
+	if err != nil {
+		return "", 0, err
+	}
+	if port == 0 {
+		port = DefaultPort
+	}
+	return name, port, nil
+}
+
+// ParseTarget parses a host to connect to from user input. If protocol is
+// empty, it's inferred from the port. A port missing from the input defaults
+// to the protocol's default port.
+func ParseTarget(input, protocol string) (Host, error) {
+	name, port, err := parseHost(input)
+	if err != nil {
+		return Host{}, err
+	}
+	var proto Protocol
+	switch {
+	case strings.TrimSpace(protocol) != "":
+		p, ok := NewProtocol(protocol)
+		if !ok {
+			return Host{}, fmt.Errorf("unsupported protocol")
+		}
+		proto = p
+	case port != 0:
+		proto = ProtocolForPort(port)
+	default:
+		proto = ProtocolTLS
+	}
+	if port == 0 {
+		port = proto.DefaultPort()
+	}
+	return Host{Hostname: name, Port: port, Protocol: proto}, nil
+}
+
+// parseHost returns 0 as the port if the input doesn't specify one.
+func parseHost(input string) (string, int, error) {
 	if len(input) > 200 {
-		return "", fmt.Errorf("hostname too long")
+		return "", 0, fmt.Errorf("hostname too long")
 	}
 	s := strings.TrimSpace(input)
 	if s == "" {
-		return "", fmt.Errorf("hostname can't be empty")
+		return "", 0, fmt.Errorf("hostname can't be empty")
 	}
 	split := strings.Split(s, "://")
 	if len(split) > 1 {
 		if !slices.Contains([]string{"https", "http"}, split[0]) {
-			return "", fmt.Errorf("invalid protocol")
+			return "", 0, fmt.Errorf("invalid protocol")
 		}
 		input = "https://" + split[1]
 	}
@@ -28,22 +77,141 @@ func ParseHostname(input string) (string, error) {
 
 	u, err := url.Parse(input)
 	if err != nil {
-		return "", fmt.Errorf("invalid url: %v", err)
+		return "", 0, fmt.Errorf("invalid url: %v", err)
 	}
 	dn := u.Hostname()
 	if dn == "" {
-		return "", fmt.Errorf("invalid hostname")
+		return "", 0, fmt.Errorf("invalid hostname")
 	}
 	for _, s := range strings.Split(dn, ".") {
 		if len(s) == 0 {
-			return "", fmt.Errorf("invalid hostname")
+			return "", 0, fmt.Errorf("invalid hostname")
 		}
 		if !isAlphanumeric(rune(s[0])) || !isAlphanumeric(rune(s[len(s)-1])) {
-			return "", fmt.Errorf("illegal character in hostname")
+			return "", 0, fmt.Errorf("illegal character in hostname")
+		}
+	}
+	port := 0
+	if p := u.Port(); p != "" {
+		port, err = ParsePort(p)
+		if err != nil {
+			return "", 0, err
+		}
+	}
+
+	return strings.TrimPrefix(dn, "https://"), port, nil
+}
+
+func ParsePort(input string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port")
+	}
+	return port, nil
+}
+
+// MaxReminderDays is the largest reminder threshold of a host in days.
+const MaxReminderDays = 365
+
+// ParseReminderDays parses a comma separated list of days before expiry into
+// reminder thresholds. Empty input returns nil.
+func ParseReminderDays(input string) ([]int, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+	var days []int
+	for _, field := range strings.Split(input, ",") {
+		d, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid number of days: %s", strings.TrimSpace(field))
 		}
+		days = append(days, d)
 	}
+	return ReminderThresholds(days)
+}
+
+// ReminderThresholds converts days before expiry into reminder thresholds in
+// seconds, largest first. 0 days reminds when the certificate has expired.
+func ReminderThresholds(days []int) ([]int, error) {
+	thresholds := []int{}
+	for _, d := range days {
+		if d < 0 || d > MaxReminderDays {
+			return nil, fmt.Errorf("days must be between 0 and %d", MaxReminderDays)
+		}
+		if sec := d * 24 * 60 * 60; !slices.Contains(thresholds, sec) {
+			thresholds = append(thresholds, sec)
+		}
+	}
+	slices.Sort(thresholds)
+	slices.Reverse(thresholds)
+	return thresholds, nil
+}
+
+// MaxSnoozeDays is how many days ahead a host can be snoozed.
+const MaxSnoozeDays = 365
+
+// CheckSnooze checks that a snooze until the time ends after now, and at
+// most MaxSnoozeDays days later.
+func CheckSnooze(until, now time.Time) error {
+	if !until.After(now) {
+		return fmt.Errorf("snooze must end in the future")
+	}
+	if until.After(now.AddDate(0, 0, MaxSnoozeDays)) {
+		return fmt.Errorf("hosts can be snoozed for at most %d days", MaxSnoozeDays)
+	}
+	return nil
+}
+
+// MaxAcknowledgementNote is the maximum length of an acknowledgement note.
+const MaxAcknowledgementNote = 500
 
-	return strings.TrimPrefix(dn, "https://"), nil
+// ParseAcknowledgementNote trims the note of an acknowledgement and checks
+// its length.
+func ParseAcknowledgementNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if len(note) > MaxAcknowledgementNote {
+		return "", fmt.Errorf("note is longer than %d characters", MaxAcknowledgementNote)
+	}
+	return note, nil
+}
+
+const (
+	// MaxTags is the largest number of tags on a host.
+	MaxTags = 20
+	// MaxTagLength is the maximum length of a tag.
+	MaxTagLength = 32
+)
+
+// ParseTags parses a comma separated list of tags. Empty input returns no
+// tags.
+func ParseTags(input string) ([]string, error) {
+	return CheckTags(strings.Split(input, ","))
+}
+
+// CheckTags trims and lowercases tags, drops empty and duplicate ones and
+// checks that the rest are valid. Tags can contain letters, digits, '-', '_',
+// '.' and ':'.
+func CheckTags(tags []string) ([]string, error) {
+	result := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || slices.Contains(result, tag) {
+			continue
+		}
+		if len(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tags can be at most %d characters", MaxTagLength)
+		}
+		for _, c := range tag {
+			if !isAlphanumeric(c) && !strings.ContainsRune("-_.:", c) {
+				return nil, fmt.Errorf("invalid tag: %s", tag)
+			}
+		}
+		result = append(result, tag)
+	}
+	if len(result) > MaxTags {
+		return nil, fmt.Errorf("hosts can have at most %d tags", MaxTags)
+	}
+	return result, nil
 }
 
 func isAlphanumeric(c rune) bool {
@@ -1,7 +1,11 @@
 package hosts_test
 
 import (
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lionpuro/neverexpire/hosts"
 )
@@ -98,3 +102,263 @@ func TestParseHostname(t *testing.T) {
 		})
 	}
 }
+
+func TestParseHost(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedName string
+		expectedPort int
+		expectErr    bool
+	}{
+		{
+			name:         "Default port",
+			input:        "example.com",
+			expectedName: "example.com",
+			expectedPort: hosts.DefaultPort,
+		},
+		{
+			name:         "Custom port",
+			input:        "example.com:8443",
+			expectedName: "example.com",
+			expectedPort: 8443,
+		},
+		{
+			name:         "Custom port with protocol",
+			input:        "https://ldap.example.com:636",
+			expectedName: "ldap.example.com",
+			expectedPort: 636,
+		},
+		{
+			name:      "Port out of range",
+			input:     "example.com:70000",
+			expectErr: true,
+		},
+		{
+			name:      "Port zero",
+			input:     "example.com:0",
+			expectErr: true,
+		},
+		{
+			name:      "Non-numeric port",
+			input:     "example.com:imaps",
+			expectErr: true,
+		},
+	}
+
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			name, port, err := hosts.ParseHost(ts.input)
+			if ts.expectErr && err == nil {
+				t.Error("expected error and got none")
+			} else if !ts.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if name != ts.expectedName || port != ts.expectedPort {
+				t.Errorf(
+					"incorrect result: expected %s:%d, got %s:%d",
+					ts.expectedName, ts.expectedPort, name, port,
+				)
+			}
+		})
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		protocol  string
+		expected  hosts.Host
+		expectErr bool
+	}{
+		{
+			name:     "Defaults to TLS",
+			input:    "example.com",
+			expected: hosts.Host{Hostname: "example.com", Port: 443, Protocol: hosts.ProtocolTLS},
+		},
+		{
+			name:     "Protocol inferred from port",
+			input:    "mail.example.com:587",
+			expected: hosts.Host{Hostname: "mail.example.com", Port: 587, Protocol: hosts.ProtocolSMTP},
+		},
+		{
+			name:     "Implicit TLS port",
+			input:    "mail.example.com:993",
+			expected: hosts.Host{Hostname: "mail.example.com", Port: 993, Protocol: hosts.ProtocolTLS},
+		},
+		{
+			name:     "Default port of protocol",
+			input:    "db.example.com",
+			protocol: "postgres",
+			expected: hosts.Host{Hostname: "db.example.com", Port: 5432, Protocol: hosts.ProtocolPostgres},
+		},
+		{
+			name:     "Explicit port and protocol",
+			input:    "xmpp.example.com:5269",
+			protocol: "xmpp",
+			expected: hosts.Host{Hostname: "xmpp.example.com", Port: 5269, Protocol: hosts.ProtocolXMPP},
+		},
+		{
+			name:      "Unsupported protocol",
+			input:     "example.com",
+			protocol:  "ftp",
+			expectErr: true,
+		},
+	}
+
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			result, err := hosts.ParseTarget(ts.input, ts.protocol)
+			if ts.expectErr && err == nil {
+				t.Error("expected error and got none")
+			} else if !ts.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if result.Hostname != ts.expected.Hostname ||
+				result.Port != ts.expected.Port ||
+				result.Protocol != ts.expected.Protocol {
+				t.Errorf("incorrect result: expected %+v, got %+v", ts.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseReminderDays(t *testing.T) {
+	day := 24 * 60 * 60
+	tests := []struct {
+		name      string
+		input     string
+		expected  []int
+		expectErr bool
+	}{
+		{
+			name:     "Empty input uses user settings",
+			input:    " ",
+			expected: nil,
+		},
+		{
+			name:     "Sorted largest first",
+			input:    "3, 60,0,3",
+			expected: []int{60 * day, 3 * day, 0},
+		},
+		{
+			name:      "Negative days",
+			input:     "-1",
+			expectErr: true,
+		},
+		{
+			name:      "Too many days",
+			input:     "400",
+			expectErr: true,
+		},
+		{
+			name:      "Not a number",
+			input:     "30, soon",
+			expectErr: true,
+		},
+	}
+
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			result, err := hosts.ParseReminderDays(ts.input)
+			if ts.expectErr && err == nil {
+				t.Error("expected error and got none")
+			} else if !ts.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if !slices.Equal(result, ts.expected) || (result == nil) != (ts.expected == nil) {
+				t.Errorf("incorrect result: expected %v, got %v", ts.expected, result)
+			}
+		})
+	}
+}
+
+func TestCheckSnooze(t *testing.T) {
+	now := time.Date(2025, 6, 4, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		until     time.Time
+		expectErr bool
+	}{
+		{
+			name:  "Next week",
+			until: now.AddDate(0, 0, 7),
+		},
+		{
+			name:      "Now",
+			until:     now,
+			expectErr: true,
+		},
+		{
+			name:      "In the past",
+			until:     now.Add(-time.Hour),
+			expectErr: true,
+		},
+		{
+			name:      "Too far ahead",
+			until:     now.AddDate(0, 0, hosts.MaxSnoozeDays+1),
+			expectErr: true,
+		},
+	}
+
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			err := hosts.CheckSnooze(ts.until, now)
+			if ts.expectErr && err == nil {
+				t.Error("expected error and got none")
+			} else if !ts.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	var tooMany []string
+	for i := range hosts.MaxTags + 1 {
+		tooMany = append(tooMany, strconv.Itoa(i))
+	}
+	tests := []struct {
+		name      string
+		input     string
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:     "Empty input",
+			input:    " ",
+			expected: []string{},
+		},
+		{
+			name:     "Trimmed, lowercased and deduplicated",
+			input:    "Prod, team:web,prod,, eu-west_1.a",
+			expected: []string{"prod", "team:web", "eu-west_1.a"},
+		},
+		{
+			name:      "Invalid character",
+			input:     "prod env",
+			expectErr: true,
+		},
+		{
+			name:      "Too long",
+			input:     strings.Repeat("a", hosts.MaxTagLength+1),
+			expectErr: true,
+		},
+		{
+			name:      "Too many",
+			input:     strings.Join(tooMany, ","),
+			expectErr: true,
+		},
+	}
+
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			result, err := hosts.ParseTags(ts.input)
+			if ts.expectErr && err == nil {
+				t.Error("expected error and got none")
+			} else if !ts.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if !ts.expectErr && !slices.Equal(result, ts.expected) {
+				t.Errorf("incorrect result: expected %v, got %v", ts.expected, result)
+			}
+		})
+	}
+}
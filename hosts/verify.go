@@ -0,0 +1,158 @@
+package hosts
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"slices"
+	"time"
+)
+
+// Reason describes why a certificate failed validation.
+type Reason string
+
+const (
+	ReasonNone             Reason = ""
+	ReasonUnknownAuthority Reason = "unknown_authority"
+	ReasonHostnameMismatch Reason = "hostname_mismatch"
+	ReasonExpired          Reason = "expired"
+	ReasonNotYetValid      Reason = "not_yet_valid"
+	ReasonIncompleteChain  Reason = "incomplete_chain"
+	ReasonWeakSignature    Reason = "weak_signature"
+	ReasonOther            Reason = "other"
+)
+
+var Reasons = []Reason{
+	ReasonUnknownAuthority,
+	ReasonHostnameMismatch,
+	ReasonExpired,
+	ReasonNotYetValid,
+	ReasonIncompleteChain,
+	ReasonWeakSignature,
+	ReasonOther,
+}
+
+func (r Reason) String() string {
+	return string(r)
+}
+
+func (r Reason) Description() string {
+	switch r {
+	case ReasonNone:
+		return ""
+	case ReasonUnknownAuthority:
+		return "certificate is signed by an unknown authority"
+	case ReasonHostnameMismatch:
+		return "certificate is not valid for the hostname"
+	case ReasonExpired:
+		return "certificate has expired"
+	case ReasonNotYetValid:
+		return "certificate is not valid yet"
+	case ReasonIncompleteChain:
+		return "server didn't send the intermediate certificates"
+	case ReasonWeakSignature:
+		return "certificate is signed with an insecure algorithm"
+	default:
+		return "certificate failed verification"
+	}
+}
+
+var weakAlgorithms = []x509.SignatureAlgorithm{
+	x509.MD2WithRSA,
+	x509.MD5WithRSA,
+	x509.SHA1WithRSA,
+	x509.DSAWithSHA1,
+	x509.ECDSAWithSHA1,
+}
+
+// verifyChain verifies the certificates presented by a host, leaf first,
+// against roots. If roots is nil, the system roots are used. It returns the
+// verified chain, or the reason the verification failed.
+func verifyChain(certs []*x509.Certificate, hostname string, roots *x509.CertPool, now time.Time) ([]*x509.Certificate, Reason) {
+	if len(certs) == 0 {
+		return nil, ReasonOther
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       hostname,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	if err == nil && len(chains) > 0 {
+		return chains[0], ReasonNone
+	}
+	return nil, verifyErrorReason(err, certs, now)
+}
+
+func verifyErrorReason(err error, certs []*x509.Certificate, now time.Time) Reason {
+	var (
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+		authorityErr x509.UnknownAuthorityError
+		insecureErr  x509.InsecureAlgorithmError
+	)
+	switch {
+	case errors.As(err, &hostnameErr):
+		return ReasonHostnameMismatch
+	case errors.As(err, &invalidErr):
+		if invalidErr.Reason != x509.Expired {
+			return ReasonOther
+		}
+		if invalidErr.Cert != nil && now.Before(invalidErr.Cert.NotBefore) {
+			return ReasonNotYetValid
+		}
+		return ReasonExpired
+	case errors.As(err, &insecureErr):
+		return ReasonWeakSignature
+	case errors.As(err, &authorityErr):
+		// insecure signatures are only reported as a hint on the unknown
+		// authority error, so check the algorithms of the chain directly
+		chain := presentedChain(certs)
+		for _, c := range chain {
+			if !isSelfSigned(c) && slices.Contains(weakAlgorithms, c.SignatureAlgorithm) {
+				return ReasonWeakSignature
+			}
+		}
+		top := chain[len(chain)-1]
+		// the issuer of the last certificate wasn't sent, but the
+		// certificate points to where it can be downloaded from
+		if !isSelfSigned(top) && len(top.IssuingCertificateURL) > 0 {
+			return ReasonIncompleteChain
+		}
+		return ReasonUnknownAuthority
+	default:
+		return ReasonOther
+	}
+}
+
+// presentedChain follows the issuers of the leaf through the presented
+// certificates, returning the chain the server sent in order.
+func presentedChain(certs []*x509.Certificate) []*x509.Certificate {
+	chain := []*x509.Certificate{certs[0]}
+	for len(chain) < len(certs) {
+		current := chain[len(chain)-1]
+		if isSelfSigned(current) {
+			break
+		}
+		var issuer *x509.Certificate
+		for _, c := range certs {
+			if c != current && bytes.Equal(current.RawIssuer, c.RawSubject) {
+				issuer = c
+				break
+			}
+		}
+		if issuer == nil || slices.Contains(chain, issuer) {
+			break
+		}
+		chain = append(chain, issuer)
+	}
+	return chain
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject)
+}
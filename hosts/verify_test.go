@@ -0,0 +1,215 @@
+package hosts
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+type certOptions struct {
+	name      string
+	ca        bool
+	notBefore time.Time
+	notAfter  time.Time
+	aia       bool
+	sigAlg    x509.SignatureAlgorithm
+	rsa       bool
+}
+
+func issueCert(t *testing.T, opts certOptions, parent *testCert) *testCert {
+	t.Helper()
+	var key crypto.Signer
+	var err error
+	if opts.rsa {
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	} else {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("failed to generate serial: %v", err)
+	}
+	if opts.notBefore.IsZero() {
+		opts.notBefore = time.Now().Add(-24 * time.Hour)
+	}
+	if opts.notAfter.IsZero() {
+		opts.notAfter = time.Now().Add(90 * 24 * time.Hour)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: opts.name},
+		NotBefore:             opts.notBefore,
+		NotAfter:              opts.notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  opts.ca,
+		SignatureAlgorithm:    opts.sigAlg,
+	}
+	if opts.ca {
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.DNSNames = []string{opts.name}
+	}
+	if opts.aia {
+		tmpl.IssuingCertificateURL = []string{"http://ca.example.com/intermediate.der"}
+	}
+	issuer, signer := tmpl, key
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, key.Public(), signer)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+func TestVerifyChain(t *testing.T) {
+	now := time.Now()
+	root := issueCert(t, certOptions{name: "Test Root", ca: true, rsa: true}, nil)
+	intermediate := issueCert(t, certOptions{name: "Test Intermediate", ca: true, rsa: true}, root)
+	leaf := issueCert(t, certOptions{name: "example.com", aia: true}, intermediate)
+	expired := issueCert(t, certOptions{
+		name:      "example.com",
+		notBefore: now.Add(-48 * time.Hour),
+		notAfter:  now.Add(-24 * time.Hour),
+	}, intermediate)
+	notYetValid := issueCert(t, certOptions{
+		name:      "example.com",
+		notBefore: now.Add(24 * time.Hour),
+		notAfter:  now.Add(48 * time.Hour),
+	}, intermediate)
+	selfSigned := issueCert(t, certOptions{name: "example.com"}, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	tests := []struct {
+		name     string
+		certs    []*x509.Certificate
+		hostname string
+		roots    *x509.CertPool
+		expected Reason
+	}{
+		{
+			name:     "Valid chain",
+			certs:    []*x509.Certificate{leaf.cert, intermediate.cert},
+			hostname: "example.com",
+			roots:    roots,
+			expected: ReasonNone,
+		},
+		{
+			name:     "Hostname mismatch",
+			certs:    []*x509.Certificate{leaf.cert, intermediate.cert},
+			hostname: "www.example.org",
+			roots:    roots,
+			expected: ReasonHostnameMismatch,
+		},
+		{
+			name:     "Expired",
+			certs:    []*x509.Certificate{expired.cert, intermediate.cert},
+			hostname: "example.com",
+			roots:    roots,
+			expected: ReasonExpired,
+		},
+		{
+			name:     "Not yet valid",
+			certs:    []*x509.Certificate{notYetValid.cert, intermediate.cert},
+			hostname: "example.com",
+			roots:    roots,
+			expected: ReasonNotYetValid,
+		},
+		{
+			name:     "Missing intermediate",
+			certs:    []*x509.Certificate{leaf.cert},
+			hostname: "example.com",
+			roots:    roots,
+			expected: ReasonIncompleteChain,
+		},
+		{
+			name:     "Untrusted root",
+			certs:    []*x509.Certificate{leaf.cert, intermediate.cert, root.cert},
+			hostname: "example.com",
+			roots:    x509.NewCertPool(),
+			expected: ReasonUnknownAuthority,
+		},
+		{
+			name:     "Self-signed",
+			certs:    []*x509.Certificate{selfSigned.cert},
+			hostname: "example.com",
+			roots:    roots,
+			expected: ReasonUnknownAuthority,
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			_, reason := verifyChain(ts.certs, ts.hostname, ts.roots, now)
+			if reason != ts.expected {
+				t.Errorf("expected reason %q, got %q", ts.expected, reason)
+			}
+		})
+	}
+}
+
+func TestVerifyChainWeakSignature(t *testing.T) {
+	root := issueCert(t, certOptions{name: "Test Root", ca: true, rsa: true}, nil)
+	leaf := issueCert(t, certOptions{name: "example.com", sigAlg: x509.SHA1WithRSA}, root)
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+	_, reason := verifyChain([]*x509.Certificate{leaf.cert}, "example.com", roots, time.Now())
+	if reason != ReasonWeakSignature {
+		t.Errorf("expected reason %q, got %q", ReasonWeakSignature, reason)
+	}
+}
+
+func TestCertificateInfoExpiry(t *testing.T) {
+	now := time.Now()
+	root := issueCert(t, certOptions{name: "Test Root", ca: true}, nil)
+	intermediate := issueCert(t, certOptions{
+		name:     "Test Intermediate",
+		ca:       true,
+		notAfter: now.Add(10 * 24 * time.Hour),
+	}, root)
+	leaf := issueCert(t, certOptions{name: "example.com"}, intermediate)
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	info := certificateInfo([]*x509.Certificate{leaf.cert, intermediate.cert}, "example.com", roots)
+	if info.Status != CertificateStatusHealthy {
+		t.Fatalf("expected status %s, got %s (%s)", CertificateStatusHealthy, info.Status, info.Reason)
+	}
+	if len(info.Chain) != 2 {
+		t.Fatalf("expected 2 certificates in chain, got %d", len(info.Chain))
+	}
+	if info.ExpiresAt == nil || !info.ExpiresAt.Equal(intermediate.cert.NotAfter) {
+		t.Errorf("expected expiry of the intermediate %v, got %v", intermediate.cert.NotAfter, info.ExpiresAt)
+	}
+
+	info = certificateInfo([]*x509.Certificate{leaf.cert}, "example.org", roots)
+	if info.Status != CertificateStatusInvalid {
+		t.Errorf("expected status %s, got %s", CertificateStatusInvalid, info.Status)
+	}
+	if info.Reason == ReasonNone || info.Error != ErrCertInvalid {
+		t.Errorf("expected reason and error to be set, got %q and %v", info.Reason, info.Error)
+	}
+}
@@ -65,18 +65,7 @@ func (w *Worker) poll() error {
 				<-workers
 				wg.Done()
 			}()
-			cert, err := FetchCert(context.Background(), h.Hostname)
-			if err != nil {
-				cert = &CertificateInfo{
-					Status:    CertificateStatusOffline,
-					IssuedBy:  "n/a",
-					CheckedAt: time.Now().UTC(),
-					Error:     err,
-				}
-			}
-			host := h
-			host.Certificate = *cert
-			results <- host
+			results <- check(h)
 		}(hst)
 	}
 
@@ -85,6 +74,22 @@ func (w *Worker) poll() error {
 	return w.updateData(results)
 }
 
+// check fetches the certificate of a host. Hosts that can't be checked are
+// marked offline.
+func check(h Host) Host {
+	cert, err := FetchCert(context.Background(), h.Hostname, h.Port, h.Protocol)
+	if err != nil {
+		cert = &CertificateInfo{
+			Status:    CertificateStatusOffline,
+			IssuedBy:  "n/a",
+			CheckedAt: time.Now().UTC(),
+			Error:     err,
+		}
+	}
+	h.Certificate = *cert
+	return h
+}
+
 func (w *Worker) updateData(hostch chan Host) error {
 	hosts := make([]Host, len(hostch))
 	i := 0
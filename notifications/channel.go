@@ -0,0 +1,172 @@
+package notifications
+
+import (
+	"slices"
+	"time"
+)
+
+// EmailProvider sends notifications to the verified email address of the
+// user. Channels with it don't have a URL.
+const EmailProvider WebhookProvider = "EMAIL"
+
+// ChannelProviders are the providers channels can be created with.
+var ChannelProviders = append(slices.Clone(WebhookProviders), EmailProvider)
+
+// Severity ranks notifications for routing.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+var Severities = []Severity{
+	SeverityInfo,
+	SeverityWarning,
+	SeverityCritical,
+}
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// criticalTimeLeft is the time left before expiry from which reminders are
+// critical.
+const criticalTimeLeft = Threshold2Days * time.Second
+
+// Severity returns the severity of the notification. Failures and reminders
+// about certificates that expire within two days are critical, other
+// reminders are warnings and renewals and recoveries are informational.
+func (n Notification) Severity() Severity {
+	switch n.Type {
+	case NotificationTypeOffline, NotificationTypeInvalid:
+		return SeverityCritical
+	case NotificationTypeExpiration:
+		if n.Host != nil && n.Host.Certificate.TimeLeft() <= criticalTimeLeft {
+			return SeverityCritical
+		}
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// NotificationTypes are the types routing rules can match.
+var NotificationTypes = []NotificationType{
+	NotificationTypeExpiration,
+	NotificationTypeRenewal,
+	NotificationTypeOffline,
+	NotificationTypeInvalid,
+	NotificationTypeRecovered,
+}
+
+// ChannelFailingThreshold is how many deliveries in a row have to fail for a
+// channel to be flagged as failing.
+const ChannelFailingThreshold = 3
+
+// Channel is a destination notifications are routed to.
+type Channel struct {
+	ID        int
+	UserID    string
+	Name      string
+	Provider  WebhookProvider
+	URL       string
+	Secret    string
+	Rules     []Rule
+	CreatedAt time.Time
+	// ConsecutiveFailures is how many delivery attempts in a row have failed.
+	ConsecutiveFailures int
+	// MessageTemplate replaces the message template of the user for this
+	// channel unless it's empty.
+	MessageTemplate string
+}
+
+// Failing reports whether the latest deliveries to the channel have failed.
+func (c Channel) Failing() bool {
+	return c.ConsecutiveFailures >= ChannelFailingThreshold
+}
+
+// Rule routes the notifications of the given types that are at least as
+// severe as MinSeverity to its channel. A rule without types matches every
+// type.
+type Rule struct {
+	ID          int
+	ChannelID   int
+	Types       []NotificationType
+	MinSeverity Severity
+}
+
+func (r Rule) Matches(n Notification) bool {
+	if len(r.Types) > 0 && !slices.Contains(r.Types, n.Type) {
+		return false
+	}
+	return n.Severity() >= r.MinSeverity
+}
+
+// Matches reports whether any of the rules of the channel matches the
+// notification.
+func (c Channel) Matches(n Notification) bool {
+	return slices.ContainsFunc(c.Rules, func(r Rule) bool {
+		return r.Matches(n)
+	})
+}
+
+// Delivery is the state of sending a notification to one channel. Failed
+// deliveries are retried at NextAttemptAt until they have been attempted
+// MaxDeliveryAttempts times, after which FailedAt is set.
+type Delivery struct {
+	ID             int
+	NotificationID int
+	ChannelID      int
+	Attempts       int
+	NextAttemptAt  time.Time
+	DeliveredAt    *time.Time
+	FailedAt       *time.Time
+	// SuppressedAt is set when the delivery was held back for good because
+	// the user snoozed or acknowledged the host.
+	SuppressedAt *time.Time
+	Error        string
+	// Digest is set on expiry reminders that are sent in the next digest of
+	// the user instead of on their own.
+	Digest bool
+}
+
+// Attempt is the record of one attempt to send a delivery.
+type Attempt struct {
+	ID         int
+	DeliveryID int
+	ChannelID  int
+	// StatusCode is the HTTP status of the response, or 0 if there was no
+	// HTTP response.
+	StatusCode int
+	// Response is the beginning of the response body.
+	Response  string
+	Latency   time.Duration
+	Error     string
+	CreatedAt time.Time
+}
+
+// OutboxEntry is a delivery along with the notification and the channel it's
+// sent to.
+type OutboxEntry struct {
+	Delivery
+	Notification Notification
+	Channel      Channel
+	// Location and QuietHours are the time zone and the quiet hours of the
+	// user.
+	Location   *time.Location
+	QuietHours QuietHours
+	// MessageTemplate is the message template of the channel, or of the user
+	// if the channel has none.
+	MessageTemplate string
+}
@@ -0,0 +1,75 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChannelMatches(t *testing.T) {
+	expiringIn := func(d time.Duration) Notification {
+		n := testNotification(NotificationTypeExpiration)
+		exp := time.Now().Add(d)
+		n.Host.Certificate.ExpiresAt = &exp
+		return n
+	}
+	pagerDuty := Channel{Rules: []Rule{
+		{Types: []NotificationType{NotificationTypeExpiration}, MinSeverity: SeverityCritical},
+		{Types: []NotificationType{NotificationTypeOffline, NotificationTypeInvalid, NotificationTypeRecovered}},
+	}}
+	tests := []struct {
+		name     string
+		channel  Channel
+		notif    Notification
+		expected bool
+	}{
+		{
+			name:     "No rules",
+			channel:  Channel{},
+			notif:    testNotification(NotificationTypeOffline),
+			expected: false,
+		},
+		{
+			name:     "Rule without types matches all",
+			channel:  Channel{Rules: []Rule{{}}},
+			notif:    testNotification(NotificationTypeRenewal),
+			expected: true,
+		},
+		{
+			name:     "Reminder under 48 hours is critical",
+			channel:  pagerDuty,
+			notif:    expiringIn(36 * time.Hour),
+			expected: true,
+		},
+		{
+			name:     "Reminder two weeks before expiry is a warning",
+			channel:  pagerDuty,
+			notif:    expiringIn(14 * 24 * time.Hour),
+			expected: false,
+		},
+		{
+			name:     "Recovery matches by type",
+			channel:  pagerDuty,
+			notif:    testNotification(NotificationTypeRecovered),
+			expected: true,
+		},
+		{
+			name:     "Renewal doesn't match",
+			channel:  pagerDuty,
+			notif:    testNotification(NotificationTypeRenewal),
+			expected: false,
+		},
+		{
+			name:     "Minimum severity without types",
+			channel:  Channel{Rules: []Rule{{MinSeverity: SeverityWarning}}},
+			notif:    testNotification(NotificationTypeRecovered),
+			expected: false,
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			if matches := ts.channel.Matches(ts.notif); matches != ts.expected {
+				t.Errorf("expected %t, got %t", ts.expected, matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package notifications
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Message length limits of the providers that digests are truncated to.
+const (
+	discordMaxLength    = 2000
+	slackMaxLength      = 3500
+	mattermostMaxLength = 16000
+	googleChatMaxLength = 4000
+	// teamsMaxDigestRows keeps digest cards under the size limit of Teams.
+	teamsMaxDigestRows = 100
+)
+
+// chatText returns the body of the notification, or the digest formatted as
+// Markdown in at most limit bytes.
+func chatText(n Notification, limit int) string {
+	if n.Digest != nil {
+		return n.Digest.Markdown(limit)
+	}
+	return n.Body
+}
+
+func avatarURL() string {
+	if url := os.Getenv("WEBHOOK_AVATAR_URL"); url != "" {
+		return url
+	}
+	if os.Getenv("APP_ENV") == "production" {
+		return "https://neverexpire.lionpuro.com/assets/images/webhook-avatar.png"
+	}
+	return ""
+}
+
+var (
+	discordURL = regexp.MustCompile(
+		`^https:\/\/discord\.com\/api\/webhooks\/[0-9]{18,19}\/[a-zA-Z0-9_-]+$`,
+	)
+	slackURL = regexp.MustCompile(
+		`^https:\/\/hooks\.slack\.com\/services\/[a-zA-Z0-9]+\/[a-zA-Z0-9]+\/[a-zA-Z0-9]+$`,
+	)
+	mattermostPath = regexp.MustCompile(`^(\/[^\/]+)*\/hooks\/[a-z0-9]+$`)
+	googleChatPath = regexp.MustCompile(`^\/v1\/spaces\/[a-zA-Z0-9_-]+\/messages$`)
+)
+
+type discord struct{}
+
+func (discord) ValidateURL(url string) bool {
+	return discordURL.MatchString(url)
+}
+
+func (discord) NewRequest(n Notification, _ string) (*http.Request, error) {
+	body := map[string]string{"content": chatText(n, discordMaxLength)}
+	if url := avatarURL(); url != "" {
+		body["avatar_url"] = url
+	}
+	return newJSONRequest(n.Endpoint, body)
+}
+
+func (discord) CheckResponse(res *http.Response) error {
+	return checkStatus(res)
+}
+
+type slack struct{}
+
+func (slack) ValidateURL(url string) bool {
+	return slackURL.MatchString(url)
+}
+
+func (slack) NewRequest(n Notification, _ string) (*http.Request, error) {
+	return newJSONRequest(n.Endpoint, map[string]string{"text": chatText(n, slackMaxLength)})
+}
+
+func (slack) CheckResponse(res *http.Response) error {
+	return checkStatus(res)
+}
+
+// teams posts an Adaptive Card to a Microsoft Teams incoming webhook or a
+// Power Automate workflow.
+type teams struct{}
+
+func (teams) ValidateURL(input string) bool {
+	if !isHTTPS(input) {
+		return false
+	}
+	u, _ := url.Parse(input)
+	host := u.Hostname()
+	for _, suffix := range []string{".webhook.office.com", ".logic.azure.com", ".powerplatform.com"} {
+		if strings.HasSuffix(host, suffix) {
+			return len(u.Path) > 1
+		}
+	}
+	return false
+}
+
+func (teams) NewRequest(n Notification, _ string) (*http.Request, error) {
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    teamsCardBody(n),
+	}
+	body := map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"contentUrl":  nil,
+				"content":     card,
+			},
+		},
+	}
+	return newJSONRequest(n.Endpoint, body)
+}
+
+// teamsCardBody returns the elements of the card. Digests list the hosts of
+// each group in a fact set.
+func teamsCardBody(n Notification) []map[string]any {
+	if n.Digest == nil {
+		return []map[string]any{
+			{"type": "TextBlock", "text": n.Body, "wrap": true},
+		}
+	}
+	body := []map[string]any{
+		{"type": "TextBlock", "text": n.Digest.Summary(), "weight": "Bolder", "wrap": true},
+	}
+	rows := 0
+	for _, g := range n.Digest.Groups() {
+		if rows == teamsMaxDigestRows {
+			break
+		}
+		var facts []map[string]string
+		for _, r := range g.Rows {
+			if rows == teamsMaxDigestRows {
+				break
+			}
+			value := r.TimeLeft
+			if r.Expires != "" {
+				value += " (" + r.Expires + ")"
+			}
+			facts = append(facts, map[string]string{"title": r.Host.Address(), "value": value})
+			rows++
+		}
+		body = append(body,
+			map[string]any{
+				"type":      "TextBlock",
+				"text":      fmt.Sprintf("%s (%d)", g.Label, len(g.Rows)),
+				"weight":    "Bolder",
+				"separator": true,
+				"wrap":      true,
+			},
+			map[string]any{"type": "FactSet", "facts": facts},
+		)
+	}
+	if more := len(n.Digest.Hosts) - rows; more > 0 {
+		body = append(body, map[string]any{
+			"type": "TextBlock",
+			"text": fmt.Sprintf("... and %d more", more),
+			"wrap": true,
+		})
+	}
+	return body
+}
+
+func (teams) CheckResponse(res *http.Response) error {
+	return checkStatus(res)
+}
+
+// mattermost posts to an incoming webhook of a self-hosted Mattermost server.
+type mattermost struct{}
+
+func (mattermost) ValidateURL(input string) bool {
+	if !isHTTPS(input) {
+		return false
+	}
+	u, _ := url.Parse(input)
+	return u.RawQuery == "" && mattermostPath.MatchString(u.Path)
+}
+
+func (mattermost) NewRequest(n Notification, _ string) (*http.Request, error) {
+	body := map[string]string{"text": chatText(n, mattermostMaxLength), "username": "neverexpire"}
+	if url := avatarURL(); url != "" {
+		body["icon_url"] = url
+	}
+	return newJSONRequest(n.Endpoint, body)
+}
+
+func (mattermost) CheckResponse(res *http.Response) error {
+	return checkStatus(res)
+}
+
+// googleChat posts to a Google Chat space webhook. The URL carries the key
+// and token of the webhook in its query.
+type googleChat struct{}
+
+func (googleChat) ValidateURL(input string) bool {
+	if !isHTTPS(input) {
+		return false
+	}
+	u, _ := url.Parse(input)
+	q := u.Query()
+	return u.Host == "chat.googleapis.com" &&
+		googleChatPath.MatchString(u.Path) &&
+		q.Get("key") != "" &&
+		q.Get("token") != ""
+}
+
+func (googleChat) NewRequest(n Notification, _ string) (*http.Request, error) {
+	req, err := newJSONRequest(n.Endpoint, map[string]string{"text": chatText(n, googleChatMaxLength)})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	return req, nil
+}
+
+func (googleChat) CheckResponse(res *http.Response) error {
+	return checkStatus(res)
+}
@@ -0,0 +1,259 @@
+package notifications
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+// EventDigest is the event of digest notifications.
+const EventDigest = "digest"
+
+type DigestFrequency int
+
+const (
+	DigestOff DigestFrequency = iota
+	DigestDaily
+	DigestWeekly
+)
+
+// DigestFrequencies are the frequencies users can choose from.
+var DigestFrequencies = []DigestFrequency{DigestOff, DigestDaily, DigestWeekly}
+
+func (f DigestFrequency) String() string {
+	switch f {
+	case DigestDaily:
+		return "daily"
+	case DigestWeekly:
+		return "weekly"
+	default:
+		return "off"
+	}
+}
+
+// Label returns a human readable name for the frequency.
+func (f DigestFrequency) Label() string {
+	switch f {
+	case DigestDaily:
+		return "Daily"
+	case DigestWeekly:
+		return "Weekly"
+	default:
+		return "Off"
+	}
+}
+
+// DigestSchedule is when the expiry reminders of a user are sent as a digest
+// instead of one by one. Hour is in the time zone of the user, and Weekday is
+// only used by weekly digests.
+type DigestSchedule struct {
+	Frequency DigestFrequency
+	Hour      int
+	Weekday   time.Weekday
+}
+
+// DefaultDigestSchedule is the schedule of users who haven't enabled digests.
+var DefaultDigestSchedule = DigestSchedule{Frequency: DigestOff, Hour: 8, Weekday: time.Monday}
+
+func (s DigestSchedule) Enabled() bool {
+	return s.Frequency == DigestDaily || s.Frequency == DigestWeekly
+}
+
+// Next returns the first time after t the digest is sent. The hour and the
+// day of the week are in the location of t.
+func (s DigestSchedule) Next(t time.Time) time.Time {
+	next := time.Date(t.Year(), t.Month(), t.Day(), s.Hour, 0, 0, 0, t.Location())
+	days := 1
+	if s.Frequency == DigestWeekly {
+		next = next.AddDate(0, 0, (int(s.Weekday)-int(next.Weekday())+7)%7)
+		days = 7
+	}
+	if !next.After(t) {
+		next = next.AddDate(0, 0, days)
+	}
+	return next
+}
+
+// digestsProvider reports whether reminders sent to the provider can be
+// batched into digests. PagerDuty incidents are opened per host, so the
+// reminders are always sent one by one.
+func digestsProvider(p WebhookProvider) bool {
+	return p != PagerDutyProvider
+}
+
+// Digest is a summary of the hosts a user is reminded about.
+type Digest struct {
+	Hosts []hosts.Host
+	// Location is the time zone the expiry dates are shown in. Nil means
+	// UTC.
+	Location *time.Location
+}
+
+// DigestGroup is the hosts of a digest with the same status or roughly the
+// same time left.
+type DigestGroup struct {
+	Label string
+	Rows  []DigestRow
+}
+
+// DigestRow is a host in a digest.
+type DigestRow struct {
+	Host     hosts.Host
+	TimeLeft string
+	Expires  string
+}
+
+var digestGroupLabels = []string{
+	"Expired",
+	"Invalid certificate",
+	"Offline",
+	"Expires within 2 days",
+	"Expires within a week",
+	"Expires within 30 days",
+	"Expires later",
+}
+
+func digestGroupIndex(h hosts.Host) int {
+	left := h.Certificate.TimeLeft()
+	switch {
+	case h.Certificate.ExpiresAt != nil && left == 0:
+		return 0
+	case h.Certificate.Status == hosts.CertificateStatusInvalid:
+		return 1
+	case h.Certificate.Status != hosts.CertificateStatusHealthy:
+		return 2
+	case left <= 2*24*time.Hour:
+		return 3
+	case left <= 7*24*time.Hour:
+		return 4
+	case left <= 30*24*time.Hour:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// Groups returns the hosts grouped by status and time left, most urgent
+// first. Hosts are sorted by expiry within the groups and empty groups are
+// left out.
+func (d Digest) Groups() []DigestGroup {
+	sorted := slices.Clone(d.Hosts)
+	slices.SortStableFunc(sorted, func(a, b hosts.Host) int {
+		return cmp.Compare(a.Certificate.TimeLeft(), b.Certificate.TimeLeft())
+	})
+	groups := make([]DigestGroup, len(digestGroupLabels))
+	for i, label := range digestGroupLabels {
+		groups[i].Label = label
+	}
+	for _, h := range sorted {
+		row := DigestRow{Host: h, TimeLeft: timeLeft(h)}
+		if exp := h.Certificate.ExpiresAt; exp != nil {
+			row.Expires = formatDate(*exp, d.Location)
+		}
+		i := digestGroupIndex(h)
+		groups[i].Rows = append(groups[i].Rows, row)
+	}
+	return slices.DeleteFunc(groups, func(g DigestGroup) bool {
+		return len(g.Rows) == 0
+	})
+}
+
+// Summary returns the first line of the digest.
+func (d Digest) Summary() string {
+	if len(d.Hosts) == 1 {
+		return "neverexpire digest: 1 certificate needs attention"
+	}
+	return fmt.Sprintf("neverexpire digest: %d certificates need attention", len(d.Hosts))
+}
+
+// timeLeft returns the time left until the certificate of the host expires
+// in days, or in hours on the last day.
+func timeLeft(h hosts.Host) string {
+	if h.Certificate.ExpiresAt == nil {
+		return "unknown"
+	}
+	left := h.Certificate.TimeLeft()
+	if left == 0 {
+		return "expired"
+	}
+	hours := int(left.Hours())
+	if hours < 24 {
+		if hours == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", hours)
+	}
+	if days := hours / 24; days != 1 {
+		return fmt.Sprintf("%d days", days)
+	}
+	return "1 day"
+}
+
+// Table returns the digest as a plain text table of at most limit bytes.
+// Hosts that don't fit are counted on the last line.
+func (d Digest) Table(limit int) string {
+	width := 0
+	for _, h := range d.Hosts {
+		width = max(width, len(h.Address()))
+	}
+	type line struct {
+		text string
+		host bool
+	}
+	var lines []line
+	for _, g := range d.Groups() {
+		if len(lines) > 0 {
+			lines = append(lines, line{})
+		}
+		lines = append(lines, line{text: fmt.Sprintf("%s (%d)", g.Label, len(g.Rows))})
+		for _, r := range g.Rows {
+			text := fmt.Sprintf("  %-*s  %-9s  %s", width, r.Host.Address(), r.TimeLeft, r.Expires)
+			lines = append(lines, line{text: strings.TrimRight(text, " "), host: true})
+		}
+	}
+
+	more := func(n int) string {
+		return fmt.Sprintf("... and %d more", n)
+	}
+	var b strings.Builder
+	written := 0
+	for _, l := range lines {
+		remaining := len(d.Hosts) - written
+		if l.host {
+			remaining--
+		}
+		// leave room for counting the hosts that won't fit after the line
+		need := len(l.text) + 1
+		if remaining > 0 {
+			need += len(more(remaining))
+		}
+		if b.Len()+need > limit {
+			b.WriteString(more(len(d.Hosts) - written))
+			break
+		}
+		b.WriteString(l.text)
+		b.WriteString("\n")
+		if l.host {
+			written++
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Markdown returns the summary followed by the table in a code block, in at
+// most limit bytes.
+func (d Digest) Markdown(limit int) string {
+	head := d.Summary() + "\n```\n"
+	const tail = "\n```"
+	return head + d.Table(limit-len(head)-len(tail)) + tail
+}
+
+// Text returns the summary followed by the table, in at most limit bytes.
+func (d Digest) Text(limit int) string {
+	head := d.Summary() + "\n\n"
+	return head + d.Table(limit-len(head))
+}
@@ -0,0 +1,195 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+func TestDigestScheduleNext(t *testing.T) {
+	// Wednesday
+	now := time.Date(2025, 6, 4, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		schedule DigestSchedule
+		expected time.Time
+	}{
+		{
+			name:     "daily later today",
+			schedule: DigestSchedule{Frequency: DigestDaily, Hour: 18},
+			expected: time.Date(2025, 6, 4, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily tomorrow",
+			schedule: DigestSchedule{Frequency: DigestDaily, Hour: 8},
+			expected: time.Date(2025, 6, 5, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekly later this week",
+			schedule: DigestSchedule{Frequency: DigestWeekly, Hour: 8, Weekday: time.Friday},
+			expected: time.Date(2025, 6, 6, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekly today",
+			schedule: DigestSchedule{Frequency: DigestWeekly, Hour: 12, Weekday: time.Wednesday},
+			expected: time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekly next week",
+			schedule: DigestSchedule{Frequency: DigestWeekly, Hour: 8, Weekday: time.Wednesday},
+			expected: time.Date(2025, 6, 11, 8, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			if next := ts.schedule.Next(now); !next.Equal(ts.expected) {
+				t.Errorf("expected %s, got %s", ts.expected, next)
+			}
+		})
+	}
+}
+
+func TestDigestScheduleNextTimeZone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	// 19:30 in Tokyo
+	now := time.Date(2025, 6, 4, 10, 30, 0, 0, time.UTC)
+	s := DigestSchedule{Frequency: DigestDaily, Hour: 8}
+	expected := time.Date(2025, 6, 4, 23, 0, 0, 0, time.UTC)
+	if next := s.Next(now.In(tokyo)); !next.Equal(expected) {
+		t.Errorf("expected %s, got %s", expected, next.UTC())
+	}
+}
+
+func TestDigestGroups(t *testing.T) {
+	day := 24 * time.Hour
+	d := Digest{Hosts: []hosts.Host{
+		*testHost("later.example.com", hosts.CertificateStatusHealthy, 20*day),
+		*testHost("soon.example.com", hosts.CertificateStatusHealthy, day+time.Hour),
+		*testHost("expired.example.com", hosts.CertificateStatusHealthy, -day),
+		*testHost("offline.example.com", hosts.CertificateStatusOffline, 60*day),
+		*testHost("sooner.example.com", hosts.CertificateStatusHealthy, 5*time.Hour),
+	}}
+	groups := d.Groups()
+	expected := []struct {
+		label string
+		hosts []string
+	}{
+		{label: "Expired", hosts: []string{"expired.example.com"}},
+		{label: "Offline", hosts: []string{"offline.example.com"}},
+		{label: "Expires within 2 days", hosts: []string{"sooner.example.com", "soon.example.com"}},
+		{label: "Expires within 30 days", hosts: []string{"later.example.com"}},
+	}
+	if len(groups) != len(expected) {
+		t.Fatalf("expected %d groups, got %d", len(expected), len(groups))
+	}
+	for i, g := range groups {
+		if g.Label != expected[i].label {
+			t.Errorf("expected group %q, got %q", expected[i].label, g.Label)
+		}
+		var names []string
+		for _, r := range g.Rows {
+			names = append(names, r.Host.Hostname)
+		}
+		if strings.Join(names, ",") != strings.Join(expected[i].hosts, ",") {
+			t.Errorf("expected hosts %v in %q, got %v", expected[i].hosts, g.Label, names)
+		}
+	}
+	if r := groups[2].Rows[0]; r.TimeLeft != "4 hours" {
+		t.Errorf("expected 4 hours left, got %q", r.TimeLeft)
+	}
+}
+
+func TestDigestTableTruncated(t *testing.T) {
+	var d Digest
+	for i := range 200 {
+		d.Hosts = append(d.Hosts, *testHost(fmt.Sprintf("host-%03d.example.com", i), hosts.CertificateStatusHealthy, 10*24*time.Hour))
+	}
+	text := d.Markdown(discordMaxLength)
+	if len(text) > discordMaxLength {
+		t.Fatalf("expected at most %d bytes, got %d", discordMaxLength, len(text))
+	}
+	if !strings.HasPrefix(text, "neverexpire digest: 200 certificates need attention") {
+		t.Errorf("expected the summary first, got %q", text[:60])
+	}
+	if !strings.Contains(text, "... and ") || !strings.HasSuffix(text, "\n```") {
+		t.Errorf("expected the table to be truncated inside the code block, got %q", text[len(text)-60:])
+	}
+	if full := d.Table(1 << 20); strings.Contains(full, "more") || strings.Count(full, "\n") != 200 {
+		t.Errorf("expected every host in the table when it fits")
+	}
+}
+
+func TestProviderDigests(t *testing.T) {
+	d := Digest{Hosts: []hosts.Host{
+		*testHost("a.example.com", hosts.CertificateStatusHealthy, 3*24*time.Hour),
+		*testHost("b.example.com", hosts.CertificateStatusInvalid, 40*24*time.Hour),
+	}}
+	n := Notification{
+		UserID: "user-1",
+		Type:   NotificationTypeExpiration,
+		Body:   d.Summary(),
+		Digest: &d,
+	}
+	tests := []struct {
+		provider WebhookProvider
+		check    func(t *testing.T, req capturedRequest)
+	}{
+		{
+			provider: SlackProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				var body map[string]string
+				if err := json.Unmarshal(req.body, &body); err != nil {
+					t.Fatalf("failed to decode body: %v", err)
+				}
+				if !strings.Contains(body["text"], "```") || !strings.Contains(body["text"], "a.example.com") {
+					t.Errorf("expected a table in a code block, got %q", body["text"])
+				}
+			},
+		},
+		{
+			provider: TeamsProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				expectJSONField(t, req.body, d.Summary(), "attachments", 0, "content", "body", 0, "text")
+				expectJSONField(t, req.body, "Invalid certificate (1)", "attachments", 0, "content", "body", 1, "text")
+				expectJSONField(t, req.body, "b.example.com", "attachments", 0, "content", "body", 2, "facts", 0, "title")
+			},
+		},
+		{
+			provider: NtfyProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				if !strings.Contains(string(req.body), "Expires within a week (1)") {
+					t.Errorf("expected a table, got %q", req.body)
+				}
+				if title := req.header.Get("Title"); title != "neverexpire digest" {
+					t.Errorf("expected digest title, got %q", title)
+				}
+			},
+		},
+		{
+			provider: GenericProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				expectJSONField(t, req.body, EventDigest, "event")
+				expectJSONField(t, req.body, "b.example.com", "hosts", 0, "hostname")
+				expectJSONField(t, req.body, "a.example.com", "hosts", 1, "hostname")
+			},
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.provider.Label(), func(t *testing.T) {
+			p, _ := ts.provider.Provider()
+			req, err := deliver(t, p, n, EventDigest, http.StatusOK)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ts.check(t, req)
+		})
+	}
+}
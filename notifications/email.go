@@ -0,0 +1,280 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/tls"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/lionpuro/neverexpire/config"
+)
+
+//go:embed templates
+var emailTemplates embed.FS
+
+var (
+	htmlTmpl = htmltemplate.Must(htmltemplate.ParseFS(emailTemplates, "templates/*.html"))
+	textTmpl = texttemplate.Must(texttemplate.ParseFS(emailTemplates, "templates/*.txt"))
+)
+
+// Email is a message with a plain text and an HTML version of the body.
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends emails through an SMTP server.
+type Mailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	appURL   string
+	// timeout bounds the whole SMTP session, from dialing to QUIT.
+	timeout time.Duration
+}
+
+// NewMailer returns a mailer configured by conf, or nil if SMTP hasn't been
+// configured.
+func NewMailer(conf *config.Config) *Mailer {
+	if conf.SMTPHost == "" {
+		return nil
+	}
+	port := conf.SMTPPort
+	if port == "" {
+		port = "587"
+	}
+	return &Mailer{
+		addr:     net.JoinHostPort(conf.SMTPHost, port),
+		host:     conf.SMTPHost,
+		username: conf.SMTPUsername,
+		password: conf.SMTPPassword,
+		from:     conf.SMTPFrom,
+		appURL:   conf.AppURL,
+		timeout:  30 * time.Second,
+	}
+}
+
+// Send sends the email. The connection is upgraded with STARTTLS when the
+// server supports it.
+func (m *Mailer) Send(email Email) error {
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %v", err)
+	}
+	msg, err := newMessage(from, email, time.Now())
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", m.addr, m.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(m.timeout)); err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return m.send(c, from.Address, email.To, msg)
+}
+
+// send runs the SMTP session the same way as smtp.SendMail.
+func (m *Mailer) send(c *smtp.Client, from, to string, msg []byte) error {
+	if err := c.Hello("localhost"); err != nil {
+		return err
+	}
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// SendVerification sends the link that verifies the email address of a user.
+func (m *Mailer) SendVerification(to, token string) error {
+	data := map[string]string{
+		"Link": fmt.Sprintf("%s/settings/email/verify?token=%s", m.appURL, token),
+	}
+	email, err := renderEmail(to, "Verify your email address", "verify", data)
+	if err != nil {
+		return err
+	}
+	return m.Send(email)
+}
+
+// SendTest sends a test notification to verify the email channel works.
+func (m *Mailer) SendTest(to string) error {
+	data := map[string]any{
+		"Message":     testMessage,
+		"SettingsURL": m.appURL + "/settings",
+	}
+	email, err := renderEmail(to, "Test notification from neverexpire", "notification", data)
+	if err != nil {
+		return err
+	}
+	return m.Send(email)
+}
+
+// SendNotification sends the notification to its email recipient.
+func (m *Mailer) SendNotification(n Notification) error {
+	if n.Digest != nil {
+		return m.sendDigest(n.Email, *n.Digest)
+	}
+	data := map[string]any{
+		"Message":     n.Body,
+		"Host":        n.Host,
+		"SettingsURL": m.appURL + "/settings",
+	}
+	if n.Host != nil {
+		data["HostURL"] = fmt.Sprintf("%s/hosts/%d", m.appURL, n.Host.ID)
+		if exp := n.Host.Certificate.ExpiresAt; exp != nil {
+			data["Expires"] = formatTime(*exp, n.Location)
+		}
+	}
+	email, err := renderEmail(n.Email, emailSubject(n), "notification", data)
+	if err != nil {
+		return err
+	}
+	return m.Send(email)
+}
+
+func (m *Mailer) sendDigest(to string, d Digest) error {
+	data := map[string]any{
+		"Summary":     d.Summary(),
+		"Groups":      d.Groups(),
+		"HostsURL":    m.appURL + "/hosts",
+		"SettingsURL": m.appURL + "/settings",
+	}
+	subject := fmt.Sprintf("Certificate digest: %d need attention", len(d.Hosts))
+	email, err := renderEmail(to, subject, "digest", data)
+	if err != nil {
+		return err
+	}
+	return m.Send(email)
+}
+
+func emailSubject(n Notification) string {
+	if n.Host == nil {
+		return "Notification from neverexpire"
+	}
+	name := n.Host.Address()
+	switch n.Type {
+	case NotificationTypeExpiration:
+		if n.Host.Certificate.TimeLeft() == 0 {
+			return fmt.Sprintf("Certificate for %s has expired", name)
+		}
+		return fmt.Sprintf("Certificate for %s expires soon", name)
+	case NotificationTypeRenewal:
+		return fmt.Sprintf("Certificate for %s was renewed", name)
+	case NotificationTypeOffline:
+		return fmt.Sprintf("%s is unreachable", name)
+	case NotificationTypeInvalid:
+		return fmt.Sprintf("Certificate for %s is invalid", name)
+	case NotificationTypeRecovered:
+		return fmt.Sprintf("%s is healthy again", name)
+	default:
+		return fmt.Sprintf("Notification about %s", name)
+	}
+}
+
+func renderEmail(to, subject, name string, data any) (Email, error) {
+	var text, html bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return Email{}, err
+	}
+	if err := htmlTmpl.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return Email{}, err
+	}
+	return Email{To: to, Subject: subject, Text: text.String(), HTML: html.String()}, nil
+}
+
+// newMessage formats the email as a multipart/alternative MIME message.
+func newMessage(from *mail.Address, email Email, date time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain; charset=utf-8", content: email.Text},
+		{contentType: "text/html; charset=utf-8", content: email.HTML},
+	}
+	for _, p := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	headers := []struct{ key, value string }{
+		{"From", from.String()},
+		{"To", email.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", email.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", mw.Boundary())},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h.key, h.value)
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,206 @@
+package notifications
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lionpuro/neverexpire/config"
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+func TestMailerSendNotification(t *testing.T) {
+	addr, received := smtpSink(t)
+	host, port, _ := net.SplitHostPort(addr)
+	mailer := NewMailer(&config.Config{
+		AppURL:   "https://neverexpire.example.com",
+		SMTPHost: host,
+		SMTPPort: port,
+		SMTPFrom: "neverexpire <notifications@example.com>",
+	})
+	expires := time.Now().Add(72 * time.Hour).UTC()
+	notif := Notification{
+		Email: "user@example.com",
+		Type:  NotificationTypeExpiration,
+		Body:  "TLS certificate for example.com will expire in 3 days",
+		Host: &hosts.Host{
+			ID:       7,
+			Hostname: "example.com",
+			Port:     443,
+			Certificate: hosts.CertificateInfo{
+				Status:    hosts.CertificateStatusHealthy,
+				IssuedBy:  "Test CA",
+				ExpiresAt: &expires,
+			},
+		},
+	}
+	if err := mailer.SendNotification(notif); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(<-received))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	if to := msg.Header.Get("To"); to != notif.Email {
+		t.Errorf("expected recipient %s, got %s", notif.Email, to)
+	}
+	if subject := msg.Header.Get("Subject"); subject != "Certificate for example.com expires soon" {
+		t.Errorf("unexpected subject: %s", subject)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type: %s", msg.Header.Get("Content-Type"))
+	}
+	parts := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+		b, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[ct] = string(b)
+	}
+	for _, ct := range []string{"text/plain", "text/html"} {
+		body, ok := parts[ct]
+		if !ok {
+			t.Errorf("missing %s part", ct)
+			continue
+		}
+		if !strings.Contains(body, notif.Body) {
+			t.Errorf("%s part doesn't contain the message", ct)
+		}
+		if !strings.Contains(body, "https://neverexpire.example.com/hosts/7") {
+			t.Errorf("%s part doesn't link to the host", ct)
+		}
+	}
+}
+
+func TestMailerSendTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	// Accept connections but never send the greeting.
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	mailer := NewMailer(&config.Config{
+		SMTPHost: host,
+		SMTPPort: port,
+		SMTPFrom: "notifications@example.com",
+	})
+	mailer.timeout = 100 * time.Millisecond
+
+	done := make(chan error, 1)
+	go func() { done <- mailer.Send(Email{To: "user@example.com", Subject: "Test"}) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected error and got none")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send didn't return after the timeout")
+	}
+}
+
+// smtpSink accepts a single SMTP session and sends the message data to the
+// returned channel.
+func smtpSink(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 end data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(l, "."))
+				}
+				received <- data.String()
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return l.Addr().String(), received
+}
+
+func TestRenderDigestEmail(t *testing.T) {
+	d := Digest{Hosts: []hosts.Host{
+		*testHost("a.example.com", hosts.CertificateStatusHealthy, 3*24*time.Hour),
+		*testHost("b.example.com", hosts.CertificateStatusOffline, 40*24*time.Hour),
+	}}
+	data := map[string]any{
+		"Summary":     d.Summary(),
+		"Groups":      d.Groups(),
+		"HostsURL":    "https://neverexpire.example.com/hosts",
+		"SettingsURL": "https://neverexpire.example.com/settings",
+	}
+	email, err := renderEmail("user@example.com", "Certificate digest", "digest", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, body := range []string{email.Text, email.HTML} {
+		for _, want := range []string{"Offline (1)", "b.example.com", "Expires within a week (1)", "a.example.com"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("expected %q in the email:\n%s", want, body)
+			}
+		}
+	}
+}
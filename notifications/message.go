@@ -0,0 +1,243 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+const (
+	// MaxTemplateLength is the maximum length of a message template in bytes.
+	MaxTemplateLength = 2000
+	// MaxMessageLength is the maximum length of a rendered message in bytes.
+	MaxMessageLength = 4000
+)
+
+var errMessageTooLong = fmt.Errorf("message is longer than %d characters", MaxMessageLength)
+
+// MessageData is what message templates are executed with.
+type MessageData struct {
+	// Type is the type of the notification: expiration, renewal, offline,
+	// invalid or recovered.
+	Type string
+	// Message is the default message of the notification.
+	Message  string
+	Hostname string
+	Port     int
+	Address  string
+	// Expires is the expiry date and time of the certificate in the time
+	// zone of the user, or empty if it's unknown.
+	Expires  string
+	DaysLeft int
+	Issuer   string
+	Status   string
+	// URL is the link to the host in the app.
+	URL string
+}
+
+func newMessageData(n Notification, appURL string) MessageData {
+	data := MessageData{
+		Type:    n.Type.String(),
+		Message: n.Body,
+	}
+	h := n.Host
+	if h == nil {
+		return data
+	}
+	data.Hostname = h.Hostname
+	data.Port = h.Port
+	data.Address = h.Address()
+	data.Issuer = h.Certificate.IssuedBy
+	data.Status = h.Certificate.Status.String()
+	data.URL = fmt.Sprintf("%s/hosts/%d", appURL, h.ID)
+	if exp := h.Certificate.ExpiresAt; exp != nil {
+		data.Expires = formatTime(*exp, n.Location)
+		data.DaysLeft = int(h.Certificate.TimeLeft().Hours() / 24)
+	}
+	return data
+}
+
+// ParseMessageTemplate parses a message template and checks that it renders
+// a message for an example reminder.
+func ParseMessageTemplate(text string) (*template.Template, error) {
+	if len(text) > MaxTemplateLength {
+		return nil, fmt.Errorf("template is longer than %d characters", MaxTemplateLength)
+	}
+	tmpl, err := template.New("message").
+		Option("missingkey=error").
+		Funcs(messageFuncs).
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	if err := checkNode(tmpl.Root); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	msg, err := executeMessage(tmpl, exampleReminder(time.UTC), "")
+	if err != nil {
+		return nil, err
+	}
+	if msg == "" {
+		return nil, fmt.Errorf("template renders an empty message")
+	}
+	return tmpl, nil
+}
+
+// PreviewMessage renders the template with an example reminder with dates in
+// loc. An empty template previews the default message.
+func PreviewMessage(text string, loc *time.Location, appURL string) (string, error) {
+	if text == "" {
+		return exampleReminder(loc).Body, nil
+	}
+	tmpl, err := ParseMessageTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	return executeMessage(tmpl, exampleReminder(loc), appURL)
+}
+
+// renderMessage renders the message of the notification with the template.
+func renderMessage(text string, n Notification, appURL string) (string, error) {
+	tmpl, err := ParseMessageTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	msg, err := executeMessage(tmpl, n, appURL)
+	if err != nil {
+		return "", err
+	}
+	if msg == "" {
+		return "", fmt.Errorf("template renders an empty message")
+	}
+	return msg, nil
+}
+
+func executeMessage(tmpl *template.Template, n Notification, appURL string) (string, error) {
+	var buf limitedBuilder
+	if err := tmpl.Execute(&buf, newMessageData(n, appURL)); err != nil {
+		if errors.Is(err, errMessageTooLong) {
+			return "", errMessageTooLong
+		}
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// checkNode rejects the actions that could make a template run for long:
+// loops and calls to other templates, which can be recursive. Without them a
+// template runs in time bounded by its length.
+func checkNode(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkNode(child); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return checkBranch(n.BranchNode)
+	case *parse.WithNode:
+		return checkBranch(n.BranchNode)
+	case *parse.RangeNode:
+		return errors.New("range isn't supported")
+	case *parse.TemplateNode:
+		return errors.New("calling templates isn't supported")
+	}
+	return nil
+}
+
+func checkBranch(b parse.BranchNode) error {
+	if err := checkNode(b.List); err != nil {
+		return err
+	}
+	return checkNode(b.ElseList)
+}
+
+// limitedBuilder is a strings.Builder that fails once more than
+// MaxMessageLength bytes are written to it.
+type limitedBuilder struct {
+	strings.Builder
+}
+
+func (b *limitedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > MaxMessageLength {
+		return 0, errMessageTooLong
+	}
+	return b.Builder.Write(p)
+}
+
+// messageFuncs replace the builtin functions of templates that build strings
+// with ones that fail on results longer than MaxMessageLength, so that a
+// template can't grow a variable until it runs out of memory.
+var messageFuncs = template.FuncMap{
+	"print":    limitFunc(fmt.Sprint),
+	"println":  limitFunc(fmt.Sprintln),
+	"printf":   limitedPrintf,
+	"html":     limitFunc(template.HTMLEscaper),
+	"js":       limitFunc(template.JSEscaper),
+	"urlquery": limitFunc(template.URLQueryEscaper),
+}
+
+func limitFunc(fn func(...any) string) func(...any) (string, error) {
+	return func(args ...any) (string, error) {
+		return limitString(fn(args...))
+	}
+}
+
+func limitString(s string) (string, error) {
+	if len(s) > MaxMessageLength {
+		return "", errMessageTooLong
+	}
+	return s, nil
+}
+
+// formatWidth matches the width and precision of the verbs of a format.
+var formatWidth = regexp.MustCompile(`%[-+# 0]*(?:\[\d+\])?(\*|\d+)?(?:\.(?:\[\d+\])?(\*|\d+)?)?`)
+
+// limitedPrintf is fmt.Sprintf that fails before formatting when the width
+// or the precision of a verb could make the result too long.
+func limitedPrintf(format string, args ...any) (string, error) {
+	for _, m := range formatWidth.FindAllStringSubmatch(format, -1) {
+		for _, size := range m[1:] {
+			if size == "" {
+				continue
+			}
+			if n, err := strconv.Atoi(size); err != nil || n > MaxMessageLength {
+				return "", errMessageTooLong
+			}
+		}
+	}
+	return limitString(fmt.Sprintf(format, args...))
+}
+
+// exampleReminder returns a reminder about a certificate that expires in two
+// weeks, with dates in loc.
+func exampleReminder(loc *time.Location) Notification {
+	expires := time.Now().UTC().Add(14*24*time.Hour + time.Hour).Truncate(time.Hour)
+	h := hosts.Host{
+		ID:       1,
+		Hostname: "example.com",
+		Port:     443,
+		Certificate: hosts.CertificateInfo{
+			IssuedBy:  "Let's Encrypt",
+			Status:    hosts.CertificateStatusHealthy,
+			ExpiresAt: &expires,
+		},
+	}
+	return Notification{
+		Type:     NotificationTypeExpiration,
+		Body:     formatReminderMsg(h, loc),
+		Host:     &h,
+		Location: loc,
+	}
+}
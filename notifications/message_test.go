@@ -0,0 +1,89 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+func TestParseMessageTemplate(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		valid bool
+	}{
+		{name: "fields", text: "{{.Hostname}} expires in {{.DaysLeft}} days: {{.URL}}", valid: true},
+		{name: "conditional", text: `{{if eq .Type "expiration"}}{{.Address}} expires {{.Expires}}{{else}}{{.Message}}{{end}}`, valid: true},
+		{name: "syntax error", text: "{{.Hostname", valid: false},
+		{name: "unknown field", text: "{{.Domain}} expires soon", valid: false},
+		{name: "unknown function", text: "{{upper .Hostname}}", valid: false},
+		{name: "empty message", text: `{{if eq .Type "offline"}}{{.Message}}{{end}}`, valid: false},
+		{name: "too long", text: strings.Repeat("a", MaxTemplateLength+1), valid: false},
+		{name: "range", text: "{{range 300000000}}{{end}}x", valid: false},
+		{name: "nested range", text: "{{if .Hostname}}{{range 10}}x{{end}}{{end}}", valid: false},
+		{name: "recursive template", text: `{{define "a"}}{{template "a" .}}{{template "a" .}}{{end}}{{template "a" .}}`, valid: false},
+		{name: "printf", text: `{{printf "%s expires in %3d days" .Address .DaysLeft}}`, valid: true},
+		{name: "printf width", text: `{{printf "%999999999d" 1}}`, valid: false},
+		{name: "printf star width", text: `{{printf "%*d" 999999999 1}}`, valid: false},
+		{name: "long output", text: "{{.Message}}" + strings.Repeat("{{.Message}}{{.Message}}", 60), valid: false},
+		{name: "growing variable", text: "{{$a := .Message}}" + strings.Repeat("{{$a = print $a $a}}", 40) + "{{$a}}", valid: false},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			_, err := ParseMessageTemplate(ts.text)
+			if ts.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !ts.valid && err == nil {
+				t.Error("expected error and got none")
+			}
+		})
+	}
+}
+
+func TestRenderMessage(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	expires := time.Now().Add(10*24*time.Hour + time.Hour).Truncate(time.Minute).UTC()
+	h := hosts.Host{
+		ID:       7,
+		Hostname: "example.com",
+		Port:     8443,
+		Certificate: hosts.CertificateInfo{
+			IssuedBy:  "Let's Encrypt",
+			Status:    hosts.CertificateStatusHealthy,
+			ExpiresAt: &expires,
+		},
+	}
+	n := Notification{
+		Type:     NotificationTypeExpiration,
+		Body:     "default",
+		Host:     &h,
+		Location: helsinki,
+	}
+	text := "{{.Address}} ({{.Issuer}}, {{.Status}}) expires in {{.DaysLeft}} days on {{.Expires}}: {{.URL}} [{{.Type}}: {{.Message}}]"
+	msg, err := renderMessage(text, n, "https://neverexpire.example")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "example.com:8443 (Let's Encrypt, healthy) expires in 10 days on " +
+		expires.In(helsinki).Format("2006-01-02 15:04 MST") +
+		": https://neverexpire.example/hosts/7 [expiration: default]"
+	if msg != expected {
+		t.Errorf("expected %q, got %q", expected, msg)
+	}
+}
+
+func TestPreviewMessageDefault(t *testing.T) {
+	msg, err := PreviewMessage("", time.UTC, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(msg, "TLS certificate for example.com will expire in 14 days") {
+		t.Errorf("expected the default message, got %q", msg)
+	}
+}
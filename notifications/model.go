@@ -1,22 +1,63 @@
 package notifications
 
-import "time"
+import (
+	"time"
+
+	"github.com/lionpuro/neverexpire/hosts"
+)
 
 type NotificationType int
 
 const (
 	NotificationTypeExpiration NotificationType = iota
+	NotificationTypeRenewal
+	NotificationTypeOffline
+	NotificationTypeInvalid
+	NotificationTypeRecovered
 )
 
+// failureTypes are the types of the notifications sent when a host starts
+// failing its checks.
+var failureTypes = []int{
+	int(NotificationTypeOffline),
+	int(NotificationTypeInvalid),
+}
+
 func (t NotificationType) String() string {
 	switch t {
 	case NotificationTypeExpiration:
 		return "expiration"
+	case NotificationTypeRenewal:
+		return "renewal"
+	case NotificationTypeOffline:
+		return "offline"
+	case NotificationTypeInvalid:
+		return "invalid"
+	case NotificationTypeRecovered:
+		return "recovered"
+	}
+	return ""
+}
+
+// Label returns a human readable name for the notification type.
+func (t NotificationType) Label() string {
+	switch t {
+	case NotificationTypeExpiration:
+		return "Expiry reminders"
+	case NotificationTypeRenewal:
+		return "Renewals"
+	case NotificationTypeOffline:
+		return "Host offline"
+	case NotificationTypeInvalid:
+		return "Invalid certificate"
+	case NotificationTypeRecovered:
+		return "Recoveries"
 	}
 	return ""
 }
 
 type Notification struct {
+	ID           int              `db:"id"`
 	Endpoint     string           `db:"endpoint"`
 	UserID       string           `db:"user_id"`
 	HostID       int              `db:"host_id"`
@@ -24,8 +65,33 @@ type Notification struct {
 	Body         string           `db:"body"`
 	Due          time.Time        `db:"due"`
 	DeliveredAt  *time.Time       `db:"delivered_at"`
-	Attempts     int              `db:"attempts"`
 	DeletedAfter time.Time        `db:"deleted_after"`
+	Provider     WebhookProvider  `db:"-"`
+	Secret       string           `db:"-"`
+	Email        string           `db:"-"`
+	Host         *hosts.Host      `db:"-"`
+	// Location is the time zone of the user, which dates in messages are
+	// shown in. Nil means UTC.
+	Location *time.Location `db:"-"`
+	// Digest is set on the digests that batch the expiry reminders of the
+	// user.
+	Digest *Digest `db:"-"`
+}
+
+// Suppressed reports whether the notification is held back at now because
+// the user has snoozed or acknowledged its host after the notification was
+// due. Either only holds until the host serves a new certificate, and neither
+// holds back notifications that become due later, such as a reminder at the
+// next threshold.
+func (n Notification) Suppressed(now time.Time) bool {
+	h := n.Host
+	if h == nil {
+		return false
+	}
+	if at := h.Settings.SnoozedAt; h.Snoozed(now) && at != nil && !n.Due.After(*at) {
+		return true
+	}
+	return h.Acknowledged() && !n.Due.After(*h.Settings.AcknowledgedAt)
 }
 
 type AppNotification struct {
@@ -40,6 +106,12 @@ type AppNotification struct {
 	Attempts     int              `db:"attempts"`
 	DeletedAfter time.Time        `db:"deleted_after"`
 	CreatedAt    time.Time        `db:"created_at"`
+	// Acknowledged is set when the user has acknowledged the host since the
+	// notification was due.
+	Acknowledged bool `db:"acknowledged"`
+	// SnoozedUntil is when the snooze of the host ends, or nil if the host
+	// isn't snoozed or was snoozed before the notification was due.
+	SnoozedUntil *time.Time `db:"snoozed_until"`
 }
 
 type NotificationUpdate struct {
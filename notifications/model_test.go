@@ -0,0 +1,87 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+// testHost returns a host with a certificate in the status that expires after
+// left.
+func testHost(name string, status hosts.CertificateStatus, left time.Duration) *hosts.Host {
+	expires := time.Now().Add(left).UTC()
+	return &hosts.Host{
+		Hostname: name,
+		Port:     443,
+		Certificate: hosts.CertificateInfo{
+			Status:    status,
+			ExpiresAt: &expires,
+		},
+	}
+}
+
+func TestNotificationSuppressed(t *testing.T) {
+	now := time.Date(2025, 6, 4, 10, 30, 0, 0, time.UTC)
+	later := now.Add(24 * time.Hour)
+	earlier := now.Add(-24 * time.Hour)
+	host := func(settings hosts.HostSettings) *hosts.Host {
+		h := testHost("example.com", hosts.CertificateStatusHealthy, 10*24*time.Hour)
+		h.Certificate.Signature = "current"
+		h.Settings = settings
+		return h
+	}
+	tests := []struct {
+		name     string
+		notif    Notification
+		expected bool
+	}{
+		{
+			name:     "snoozed after due",
+			notif:    Notification{Due: earlier, Host: host(hosts.HostSettings{SnoozedAt: &now, SnoozedUntil: &later, SnoozedFingerprint: "current"})},
+			expected: true,
+		},
+		{
+			name:     "snooze ended",
+			notif:    Notification{Due: earlier, Host: host(hosts.HostSettings{SnoozedAt: &earlier, SnoozedUntil: &earlier, SnoozedFingerprint: "current"})},
+			expected: false,
+		},
+		{
+			name:     "due after snooze",
+			notif:    Notification{Due: now, Host: host(hosts.HostSettings{SnoozedAt: &earlier, SnoozedUntil: &later, SnoozedFingerprint: "current"})},
+			expected: false,
+		},
+		{
+			name:     "snoozed before renewal",
+			notif:    Notification{Due: earlier, Host: host(hosts.HostSettings{SnoozedAt: &now, SnoozedUntil: &later, SnoozedFingerprint: "previous"})},
+			expected: false,
+		},
+		{
+			name:     "acknowledged after due",
+			notif:    Notification{Due: earlier, Host: host(hosts.HostSettings{AcknowledgedAt: &now, AcknowledgedFingerprint: "current"})},
+			expected: true,
+		},
+		{
+			name:     "due after acknowledgement",
+			notif:    Notification{Due: later, Host: host(hosts.HostSettings{AcknowledgedAt: &now, AcknowledgedFingerprint: "current"})},
+			expected: false,
+		},
+		{
+			name:     "acknowledged before renewal",
+			notif:    Notification{Due: earlier, Host: host(hosts.HostSettings{AcknowledgedAt: &now, AcknowledgedFingerprint: "previous"})},
+			expected: false,
+		},
+		{
+			name:     "no host",
+			notif:    Notification{Due: now},
+			expected: false,
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			if suppressed := ts.notif.Suppressed(now); suppressed != ts.expected {
+				t.Errorf("expected %t, got %t", ts.expected, suppressed)
+			}
+		})
+	}
+}
@@ -1,26 +1,67 @@
 package notifications
 
 import (
+	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/lionpuro/neverexpire/logging"
 )
 
+// Reminder thresholds in seconds before the certificate expires.
+// ThresholdExpired sends the reminder when the certificate has expired.
 const (
-	ThresholdDay    = 24 * 60 * 60
-	Threshold2Days  = ThresholdDay * 2
-	ThresholdWeek   = ThresholdDay * 7
-	Threshold2Weeks = ThresholdDay * 7 * 2
+	ThresholdExpired = 0
+	ThresholdDay     = 24 * 60 * 60
+	Threshold2Days   = ThresholdDay * 2
+	ThresholdWeek    = ThresholdDay * 7
+	Threshold2Weeks  = ThresholdDay * 7 * 2
+	ThresholdMonth   = ThresholdDay * 30
 )
 
+// Thresholds are the reminder thresholds users can choose from.
+var Thresholds = []int{
+	ThresholdMonth,
+	Threshold2Weeks,
+	ThresholdWeek,
+	Threshold2Days,
+	ThresholdDay,
+	ThresholdExpired,
+}
+
+// CheckThresholds checks that each of the reminder thresholds is one of
+// Thresholds, returning them without duplicates, largest first. No thresholds
+// returns an empty, non-nil slice.
+func CheckThresholds(thresholds []int) ([]int, error) {
+	result := []int{}
+	for _, t := range thresholds {
+		if !slices.Contains(Thresholds, t) {
+			return nil, fmt.Errorf("invalid reminder threshold: %d", t)
+		}
+		if !slices.Contains(result, t) {
+			result = append(result, t)
+		}
+	}
+	slices.Sort(result)
+	slices.Reverse(result)
+	return result, nil
+}
+
 const (
-	testMessage = "Hello! Your notification webhook for neverexpire is set up correctly."
+	testMessage = "Hello! Your notification channel for neverexpire is set up correctly."
 )
 
-func SendTestNotification(url string) error {
+func SendTestNotification(provider WebhookProvider, url, secret string) error {
 	c := &http.Client{
 		Timeout: 10 * time.Second,
 	}
-	return sendNotification(logging.DefaultLogger(), c, url, testMessage)
+	notif := Notification{
+		Endpoint: url,
+		Body:     testMessage,
+		Provider: provider,
+		Secret:   secret,
+	}
+	_, err := sendNotification(logging.DefaultLogger(), c, notif, EventTest)
+	return err
 }
@@ -0,0 +1,43 @@
+package notifications
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheckThresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds []int
+		expected   []int
+		expectErr  bool
+	}{
+		{
+			name:       "no reminders",
+			thresholds: nil,
+			expected:   []int{},
+		},
+		{
+			name:       "sorted largest first",
+			thresholds: []int{ThresholdExpired, ThresholdMonth, ThresholdWeek, ThresholdMonth},
+			expected:   []int{ThresholdMonth, ThresholdWeek, ThresholdExpired},
+		},
+		{
+			name:       "not a choice",
+			thresholds: []int{10 * ThresholdDay},
+			expectErr:  true,
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			result, err := CheckThresholds(ts.thresholds)
+			if ts.expectErr && err == nil {
+				t.Error("expected error and got none")
+			} else if !ts.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if !ts.expectErr && (result == nil || !slices.Equal(result, ts.expected)) {
+				t.Errorf("expected %v, got %v", ts.expected, result)
+			}
+		})
+	}
+}
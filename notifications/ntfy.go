@@ -0,0 +1,63 @@
+package notifications
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var ntfyTopic = regexp.MustCompile(`^\/[-_A-Za-z0-9]{1,64}$`)
+
+// ntfyMaxLength is the size limit of ntfy messages that digests are truncated
+// to.
+const ntfyMaxLength = 4000
+
+// ntfy publishes to a topic of ntfy.sh or a self-hosted ntfy server. The
+// message is sent as the plain text body with the title, priority and tags
+// set in headers.
+type ntfy struct{}
+
+func (ntfy) ValidateURL(input string) bool {
+	if !isHTTPS(input) {
+		return false
+	}
+	u, _ := url.Parse(input)
+	return u.RawQuery == "" && ntfyTopic.MatchString(u.Path)
+}
+
+func (ntfy) NewRequest(n Notification, event string) (*http.Request, error) {
+	text, title := n.Body, "neverexpire"
+	if n.Digest != nil {
+		text, title = n.Digest.Text(ntfyMaxLength), "neverexpire digest"
+	}
+	req, err := http.NewRequest(http.MethodPost, n.Endpoint, strings.NewReader(text))
+	if err != nil {
+		return nil, err
+	}
+	priority, tags := "default", "lock"
+	switch {
+	case n.Digest != nil:
+		tags = "calendar"
+	case event != EventTest:
+		switch n.Type {
+		case NotificationTypeOffline, NotificationTypeInvalid:
+			priority, tags = "high", "rotating_light"
+		case NotificationTypeRecovered, NotificationTypeRenewal:
+			tags = "white_check_mark"
+		case NotificationTypeExpiration:
+			if n.Host != nil && n.Host.Certificate.TimeLeft() == 0 {
+				priority, tags = "high", "rotating_light"
+			}
+		}
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Title", title)
+	req.Header.Set("Priority", priority)
+	req.Header.Set("Tags", tags)
+	return req, nil
+}
+
+func (ntfy) CheckResponse(res *http.Response) error {
+	return checkStatus(res)
+}
@@ -0,0 +1,144 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+const (
+	pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	pagerDutyChangeURL = "https://events.pagerduty.com/v2/change/enqueue"
+)
+
+// PagerDuty integration keys are 32 characters long.
+var pagerDutyKey = regexp.MustCompile(`^[a-zA-Z0-9]{32}$`)
+
+// pagerDuty sends events to the PagerDuty Events API v2. Instead of a URL,
+// users configure the integration key of an Events API v2 integration.
+//
+// Alerts about a host share a dedup key, so PagerDuty groups repeated
+// reminders into a single incident. A recovery resolves the incident opened
+// by the failure, and a renewal resolves the incident opened by an expiry
+// reminder. Test notifications are sent as change events, which don't open
+// incidents.
+type pagerDuty struct {
+	eventsURL string
+	changeURL string
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Client      string            `json:"client,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string         `json:"summary"`
+	Source        string         `json:"source"`
+	Severity      string         `json:"severity,omitempty"`
+	Timestamp     time.Time      `json:"timestamp"`
+	Component     string         `json:"component,omitempty"`
+	CustomDetails map[string]any `json:"custom_details,omitempty"`
+}
+
+type pagerDutyChange struct {
+	RoutingKey string           `json:"routing_key"`
+	Payload    pagerDutyPayload `json:"payload"`
+}
+
+func (pagerDuty) ValidateURL(key string) bool {
+	return pagerDutyKey.MatchString(key)
+}
+
+func (p pagerDuty) NewRequest(n Notification, event string) (*http.Request, error) {
+	now := time.Now().UTC()
+	if event == EventTest {
+		return newJSONRequest(p.changeURL, pagerDutyChange{
+			RoutingKey: n.Endpoint,
+			Payload: pagerDutyPayload{
+				Summary:   n.Body,
+				Source:    "neverexpire",
+				Timestamp: now,
+			},
+		})
+	}
+	if n.Host == nil {
+		return nil, fmt.Errorf("pagerduty: notification has no host")
+	}
+	ev := pagerDutyEvent{
+		RoutingKey: n.Endpoint,
+		DedupKey:   pagerDutyDedupKey(n),
+		Client:     "neverexpire",
+	}
+	switch n.Type {
+	case NotificationTypeRecovered, NotificationTypeRenewal:
+		ev.EventAction = "resolve"
+	default:
+		ev.EventAction = "trigger"
+		ev.Payload = &pagerDutyPayload{
+			Summary:   n.Body,
+			Source:    n.Host.Address(),
+			Severity:  pagerDutySeverity(n),
+			Timestamp: now,
+			Component: "tls-certificate",
+			CustomDetails: map[string]any{
+				"status":     n.Host.Certificate.Status.String(),
+				"issuer":     n.Host.Certificate.IssuedBy,
+				"expires_at": n.Host.Certificate.ExpiresAt,
+			},
+		}
+	}
+	return newJSONRequest(p.eventsURL, ev)
+}
+
+func (pagerDuty) CheckResponse(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return nil
+	}
+	var body struct {
+		Message string   `json:"message"`
+		Errors  []string `json:"errors"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	if err := json.Unmarshal(b, &body); err != nil || body.Message == "" {
+		return fmt.Errorf("response status: %s", res.Status)
+	}
+	return fmt.Errorf("response status: %s: %s %v", res.Status, body.Message, body.Errors)
+}
+
+// pagerDutyDedupKey returns the key of the incident the notification belongs
+// to. Failures and recoveries of a host share a key, as do expiry reminders
+// and renewals.
+func pagerDutyDedupKey(n Notification) string {
+	kind := "expiry"
+	switch n.Type {
+	case NotificationTypeOffline, NotificationTypeInvalid, NotificationTypeRecovered:
+		kind = "status"
+	}
+	return fmt.Sprintf("neverexpire/%s/%d/%s", n.UserID, n.HostID, kind)
+}
+
+func pagerDutySeverity(n Notification) string {
+	switch n.Type {
+	case NotificationTypeOffline, NotificationTypeInvalid:
+		return "error"
+	case NotificationTypeExpiration:
+		left := n.Host.Certificate.TimeLeft()
+		switch {
+		case left == 0:
+			return "critical"
+		case left < 3*24*time.Hour:
+			return "error"
+		default:
+			return "warning"
+		}
+	default:
+		return "info"
+	}
+}
@@ -1,44 +1,150 @@
 package notifications
 
 import (
-	"regexp"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
 )
 
-var webhookRegexp = map[WebhookProvider]*regexp.Regexp{
-	DiscordProvider: regexp.MustCompile(
-		`^https:\/\/discord\.com\/api\/webhooks\/[0-9]{18,19}\/[a-zA-Z0-9_-]+$`,
-	),
-	SlackProvider: regexp.MustCompile(
-		`^https:\/\/hooks\.slack\.com\/services\/[a-zA-Z0-9]+\/[a-zA-Z0-9]+\/[a-zA-Z0-9]+$`,
-	),
+// Provider delivers notifications to a third-party service. Each provider
+// validates the URLs users configure, formats the requests it sends and
+// decides whether a response means the notification was delivered.
+type Provider interface {
+	// ValidateURL reports whether url can be used as the endpoint of the
+	// provider.
+	ValidateURL(url string) bool
+	// NewRequest formats the notification as a request to its endpoint.
+	// Event is the notification type, or EventTest for test notifications.
+	NewRequest(n Notification, event string) (*http.Request, error)
+	// CheckResponse returns an error if the notification wasn't accepted.
+	CheckResponse(res *http.Response) error
 }
 
 const (
-	DiscordProvider WebhookProvider = "DISCORD"
-	SlackProvider   WebhookProvider = "SLACK"
+	DiscordProvider    WebhookProvider = "DISCORD"
+	SlackProvider      WebhookProvider = "SLACK"
+	TeamsProvider      WebhookProvider = "TEAMS"
+	MattermostProvider WebhookProvider = "MATTERMOST"
+	GoogleChatProvider WebhookProvider = "GOOGLE_CHAT"
+	NtfyProvider       WebhookProvider = "NTFY"
+	PagerDutyProvider  WebhookProvider = "PAGERDUTY"
+	// GenericProvider posts a signed JSON payload to any HTTPS URL.
+	GenericProvider WebhookProvider = "WEBHOOK"
 )
 
+// WebhookProviders are the providers users can choose from, in the order they
+// are listed.
+var WebhookProviders = []WebhookProvider{
+	DiscordProvider,
+	SlackProvider,
+	TeamsProvider,
+	MattermostProvider,
+	GoogleChatProvider,
+	NtfyProvider,
+	PagerDutyProvider,
+	GenericProvider,
+}
+
+var providers = map[WebhookProvider]Provider{
+	DiscordProvider:    discord{},
+	SlackProvider:      slack{},
+	TeamsProvider:      teams{},
+	MattermostProvider: mattermost{},
+	GoogleChatProvider: googleChat{},
+	NtfyProvider:       ntfy{},
+	PagerDutyProvider:  pagerDuty{eventsURL: pagerDutyEventsURL, changeURL: pagerDutyChangeURL},
+	GenericProvider:    generic{},
+}
+
 type WebhookProvider string
 
 func (p WebhookProvider) String() string {
 	return string(p)
 }
 
+// Label returns a human readable name for the provider.
+func (p WebhookProvider) Label() string {
+	switch p {
+	case DiscordProvider:
+		return "Discord"
+	case SlackProvider:
+		return "Slack"
+	case TeamsProvider:
+		return "Microsoft Teams"
+	case MattermostProvider:
+		return "Mattermost"
+	case GoogleChatProvider:
+		return "Google Chat"
+	case NtfyProvider:
+		return "ntfy"
+	case PagerDutyProvider:
+		return "PagerDuty"
+	case GenericProvider:
+		return "Webhook (JSON)"
+	case EmailProvider:
+		return "Email"
+	default:
+		return string(p)
+	}
+}
+
+// Provider returns the implementation of the provider.
+func (p WebhookProvider) Provider() (Provider, bool) {
+	provider, ok := providers[p]
+	return provider, ok
+}
+
 func (p WebhookProvider) ValidateURL(url string) bool {
-	pattern, ok := webhookRegexp[p]
+	provider, ok := p.Provider()
 	if !ok {
 		return false
 	}
-	return pattern.MatchString(url)
+	return provider.ValidateURL(url)
 }
 
 func NewWebhookProvider(input string) (WebhookProvider, bool) {
-	switch input {
-	case string(DiscordProvider):
-		return DiscordProvider, true
-	case string(SlackProvider):
-		return SlackProvider, true
-	default:
+	p := WebhookProvider(input)
+	if _, ok := providers[p]; !ok {
 		return WebhookProvider(""), false
 	}
+	return p, true
+}
+
+func isHTTPS(input string) bool {
+	u, err := url.Parse(input)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "https" && u.Hostname() != "" && u.User == nil
+}
+
+// newJSONRequest returns a POST request with body encoded as JSON.
+func newJSONRequest(endpoint string, body any) (*http.Request, error) {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// checkStatus returns an error with the beginning of the response body if
+// the status code isn't 2xx.
+func checkStatus(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return nil
+	}
+	b, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	if msg := strings.TrimSpace(string(b)); msg != "" {
+		return fmt.Errorf("response status: %s: %s", res.Status, msg)
+	}
+	return fmt.Errorf("response status: %s", res.Status)
 }
@@ -0,0 +1,300 @@
+package notifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+type capturedRequest struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// deliver sends the notification with the provider to a test server that
+// responds with status, returning the request the server received.
+func deliver(t *testing.T, p Provider, n Notification, event string, status int) (capturedRequest, error) {
+	t.Helper()
+	received := make(chan capturedRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- capturedRequest{path: r.URL.Path, header: r.Header, body: body}
+		w.WriteHeader(status)
+		if status >= 400 {
+			_, _ = io.WriteString(w, `{"status":"invalid event","message":"Event object is invalid","errors":["Length of 'routing_key' is incorrect"]}`)
+		}
+	}))
+	defer srv.Close()
+	if pd, ok := p.(pagerDuty); ok {
+		pd.eventsURL = srv.URL + "/v2/enqueue"
+		pd.changeURL = srv.URL + "/v2/change/enqueue"
+		p = pd
+	} else {
+		n.Endpoint = srv.URL + "/hook"
+	}
+	req, err := p.NewRequest(n, event)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer res.Body.Close()
+	return <-received, p.CheckResponse(res)
+}
+
+func testNotification(typ NotificationType) Notification {
+	expires := time.Now().Add(2 * 24 * time.Hour)
+	return Notification{
+		UserID: "user-1",
+		HostID: 3,
+		Type:   typ,
+		Body:   "TLS certificate for example.com will expire in 2 days",
+		Host: &hosts.Host{
+			ID:       3,
+			Hostname: "example.com",
+			Port:     443,
+			Certificate: hosts.CertificateInfo{
+				Status:    hosts.CertificateStatusHealthy,
+				IssuedBy:  "Test CA",
+				ExpiresAt: &expires,
+			},
+		},
+	}
+}
+
+func TestProviderRequests(t *testing.T) {
+	n := testNotification(NotificationTypeExpiration)
+	tests := []struct {
+		provider WebhookProvider
+		check    func(t *testing.T, req capturedRequest)
+	}{
+		{
+			provider: DiscordProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				expectJSONField(t, req.body, n.Body, "content")
+			},
+		},
+		{
+			provider: SlackProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				expectJSONField(t, req.body, n.Body, "text")
+			},
+		},
+		{
+			provider: TeamsProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				expectJSONField(t, req.body, "message", "type")
+				expectJSONField(t, req.body, "application/vnd.microsoft.card.adaptive", "attachments", 0, "contentType")
+				expectJSONField(t, req.body, n.Body, "attachments", 0, "content", "body", 0, "text")
+			},
+		},
+		{
+			provider: MattermostProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				expectJSONField(t, req.body, n.Body, "text")
+				expectJSONField(t, req.body, "neverexpire", "username")
+			},
+		},
+		{
+			provider: GoogleChatProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				expectJSONField(t, req.body, n.Body, "text")
+			},
+		},
+		{
+			provider: NtfyProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				if string(req.body) != n.Body {
+					t.Errorf("expected body %q, got %q", n.Body, req.body)
+				}
+				if p := req.header.Get("Priority"); p != "default" {
+					t.Errorf("expected default priority, got %q", p)
+				}
+			},
+		},
+		{
+			provider: PagerDutyProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				if req.path != "/v2/enqueue" {
+					t.Errorf("expected event to be sent to the events API, got %s", req.path)
+				}
+				expectJSONField(t, req.body, "trigger", "event_action")
+				expectJSONField(t, req.body, "neverexpire/user-1/3/expiry", "dedup_key")
+				expectJSONField(t, req.body, "error", "payload", "severity")
+				expectJSONField(t, req.body, "example.com", "payload", "source")
+			},
+		},
+		{
+			provider: GenericProvider,
+			check: func(t *testing.T, req capturedRequest) {
+				expectJSONField(t, req.body, "expiration", "event")
+				expectJSONField(t, req.body, "example.com", "host", "hostname")
+				if req.header.Get(SignatureHeader) == "" {
+					t.Error("expected request to be signed")
+				}
+			},
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.provider.Label(), func(t *testing.T) {
+			p, ok := ts.provider.Provider()
+			if !ok {
+				t.Fatalf("provider %s isn't registered", ts.provider)
+			}
+			notif := n
+			notif.Endpoint = "R0UTINGKEY0000000000000000000000"
+			req, err := deliver(t, p, notif, n.Type.String(), http.StatusAccepted)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ts.check(t, req)
+		})
+	}
+}
+
+func TestProviderErrorResponses(t *testing.T) {
+	for _, wp := range WebhookProviders {
+		t.Run(wp.Label(), func(t *testing.T) {
+			p, _ := wp.Provider()
+			_, err := deliver(t, p, testNotification(NotificationTypeExpiration), EventTest, http.StatusBadRequest)
+			if err == nil {
+				t.Fatal("expected error and got none")
+			}
+			if !strings.Contains(err.Error(), "400") {
+				t.Errorf("expected error to include the status, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPagerDutyEvents(t *testing.T) {
+	p, _ := PagerDutyProvider.Provider()
+	tests := []struct {
+		name     string
+		typ      NotificationType
+		event    string
+		path     string
+		action   string
+		dedupKey string
+	}{
+		{
+			name:     "Offline host triggers an incident",
+			typ:      NotificationTypeOffline,
+			path:     "/v2/enqueue",
+			action:   "trigger",
+			dedupKey: "neverexpire/user-1/3/status",
+		},
+		{
+			name:     "Recovery resolves the failure incident",
+			typ:      NotificationTypeRecovered,
+			path:     "/v2/enqueue",
+			action:   "resolve",
+			dedupKey: "neverexpire/user-1/3/status",
+		},
+		{
+			name:     "Renewal resolves the expiry incident",
+			typ:      NotificationTypeRenewal,
+			path:     "/v2/enqueue",
+			action:   "resolve",
+			dedupKey: "neverexpire/user-1/3/expiry",
+		},
+		{
+			name:  "Test notification is a change event",
+			event: EventTest,
+			path:  "/v2/change/enqueue",
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			n := testNotification(ts.typ)
+			n.Endpoint = "R0UTINGKEY0000000000000000000000"
+			event := ts.event
+			if event == "" {
+				event = ts.typ.String()
+			}
+			req, err := deliver(t, p, n, event, http.StatusAccepted)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if req.path != ts.path {
+				t.Errorf("expected path %s, got %s", ts.path, req.path)
+			}
+			expectJSONField(t, req.body, n.Endpoint, "routing_key")
+			if ts.action != "" {
+				expectJSONField(t, req.body, ts.action, "event_action")
+				expectJSONField(t, req.body, ts.dedupKey, "dedup_key")
+			}
+		})
+	}
+}
+
+func TestValidateProviderURL(t *testing.T) {
+	tests := []struct {
+		provider WebhookProvider
+		url      string
+		valid    bool
+	}{
+		{TeamsProvider, "https://contoso.webhook.office.com/webhookb2/abc@def/IncomingWebhook/123/456", true},
+		{TeamsProvider, "https://prod-12.westus.logic.azure.com:443/workflows/abc/triggers/manual/paths/invoke?sig=x", true},
+		{TeamsProvider, "https://example.com/webhookb2/abc", false},
+		{TeamsProvider, "http://contoso.webhook.office.com/webhookb2/abc", false},
+		{MattermostProvider, "https://chat.example.com/hooks/xfp4kwbzcbgd3yx9kzpc1hyaxo", true},
+		{MattermostProvider, "https://example.com/mattermost/hooks/xfp4kwbzcbgd3yx9kzpc1hyaxo", true},
+		{MattermostProvider, "https://chat.example.com/api/v4/posts", false},
+		{GoogleChatProvider, "https://chat.googleapis.com/v1/spaces/AAAA1234/messages?key=abc&token=def", true},
+		{GoogleChatProvider, "https://chat.googleapis.com/v1/spaces/AAAA1234/messages", false},
+		{GoogleChatProvider, "https://example.com/v1/spaces/AAAA1234/messages?key=abc&token=def", false},
+		{NtfyProvider, "https://ntfy.sh/neverexpire-alerts", true},
+		{NtfyProvider, "https://ntfy.example.com/certs_prod", true},
+		{NtfyProvider, "https://ntfy.sh/", false},
+		{NtfyProvider, "https://ntfy.sh/a/b", false},
+		{PagerDutyProvider, "R0UTINGKEY0000000000000000000000", true},
+		{PagerDutyProvider, "https://events.pagerduty.com/v2/enqueue", false},
+		{PagerDutyProvider, "tooshort", false},
+	}
+	for _, ts := range tests {
+		t.Run(ts.provider.Label()+" "+ts.url, func(t *testing.T) {
+			if valid := ts.provider.ValidateURL(ts.url); valid != ts.valid {
+				t.Errorf("expected valid to be %t, got %t", ts.valid, valid)
+			}
+		})
+	}
+}
+
+// expectJSONField checks the value at the path of object keys and array
+// indexes in the JSON body.
+func expectJSONField(t *testing.T, body []byte, expected string, path ...any) {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		t.Fatalf("invalid JSON body: %v", err)
+	}
+	for _, key := range path {
+		switch k := key.(type) {
+		case string:
+			obj, ok := v.(map[string]any)
+			if !ok {
+				t.Fatalf("expected object at %v", path)
+			}
+			v = obj[k]
+		case int:
+			arr, ok := v.([]any)
+			if !ok || len(arr) <= k {
+				t.Fatalf("expected array at %v", path)
+			}
+			v = arr[k]
+		}
+	}
+	if s, ok := v.(string); !ok || s != expected {
+		t.Errorf("expected %v to be %q, got %v", path, expected, v)
+	}
+}
@@ -0,0 +1,89 @@
+package notifications
+
+import "time"
+
+// QuietHours is a daily window when notifications that can wait are held
+// back until the window ends. Start and End are hours in the time zone of the
+// user, and the window wraps around midnight when End is before Start.
+type QuietHours struct {
+	Enabled bool
+	Start   int
+	End     int
+}
+
+// DefaultQuietHours are the quiet hours shown to users who haven't enabled
+// them.
+var DefaultQuietHours = QuietHours{Enabled: false, Start: 22, End: 7}
+
+// quietHours returns the quiet hours saved as start and end, which are nil
+// when quiet hours are off.
+func quietHours(start, end *int) QuietHours {
+	if start == nil || end == nil {
+		return DefaultQuietHours
+	}
+	return QuietHours{Enabled: true, Start: *start, End: *end}
+}
+
+// Until returns the end of the window if t is within it. The hours are in
+// the location of t.
+func (q QuietHours) Until(t time.Time) (time.Time, bool) {
+	if !q.Enabled || q.Start == q.End {
+		return time.Time{}, false
+	}
+	h := t.Hour()
+	quiet := h >= q.Start && h < q.End
+	if q.Start > q.End {
+		quiet = h >= q.Start || h < q.End
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+	end := time.Date(t.Year(), t.Month(), t.Day(), q.End, 0, 0, 0, t.Location())
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, true
+}
+
+// Schedule is when notifications are sent to a user.
+type Schedule struct {
+	// Location is the time zone of the user. Quiet hours, digest hours and
+	// dates in messages are in this time zone.
+	Location   *time.Location
+	QuietHours QuietHours
+	Digest     DigestSchedule
+}
+
+// formatTime formats t as a date and a time in loc, or in UTC if loc is nil.
+func formatTime(t time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format("2006-01-02 15:04 MST")
+}
+
+// formatDate formats t as a date in loc, or in UTC if loc is nil.
+func formatDate(t time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format("2006-01-02")
+}
+
+// urgentTimeLeft is the time left before expiry from which reminders are sent
+// during quiet hours.
+const urgentTimeLeft = 24 * time.Hour
+
+// Urgent reports whether the notification is sent during quiet hours.
+// Failures and reminders about certificates that expire within a day are
+// urgent, everything else waits until the quiet hours end.
+func (n Notification) Urgent() bool {
+	switch n.Type {
+	case NotificationTypeOffline, NotificationTypeInvalid:
+		return true
+	case NotificationTypeExpiration:
+		return n.Host != nil && n.Host.Certificate.TimeLeft() <= urgentTimeLeft
+	default:
+		return false
+	}
+}
@@ -0,0 +1,125 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+func TestQuietHoursUntil(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	overnight := QuietHours{Enabled: true, Start: 22, End: 7}
+	tests := []struct {
+		name     string
+		quiet    QuietHours
+		now      time.Time
+		expected time.Time
+		ok       bool
+	}{
+		{
+			name:     "before midnight",
+			quiet:    overnight,
+			now:      time.Date(2025, 6, 4, 23, 30, 0, 0, helsinki),
+			expected: time.Date(2025, 6, 5, 7, 0, 0, 0, helsinki),
+			ok:       true,
+		},
+		{
+			name:     "after midnight",
+			quiet:    overnight,
+			now:      time.Date(2025, 6, 5, 3, 0, 0, 0, helsinki),
+			expected: time.Date(2025, 6, 5, 7, 0, 0, 0, helsinki),
+			ok:       true,
+		},
+		{
+			name:  "after the window",
+			quiet: overnight,
+			now:   time.Date(2025, 6, 5, 7, 0, 0, 0, helsinki),
+			ok:    false,
+		},
+		{
+			name:     "within a daytime window",
+			quiet:    QuietHours{Enabled: true, Start: 12, End: 14},
+			now:      time.Date(2025, 6, 5, 13, 15, 0, 0, helsinki),
+			expected: time.Date(2025, 6, 5, 14, 0, 0, 0, helsinki),
+			ok:       true,
+		},
+		{
+			name:  "outside a daytime window",
+			quiet: QuietHours{Enabled: true, Start: 12, End: 14},
+			now:   time.Date(2025, 6, 5, 23, 0, 0, 0, helsinki),
+			ok:    false,
+		},
+		{
+			name:  "disabled",
+			quiet: QuietHours{Enabled: false, Start: 22, End: 7},
+			now:   time.Date(2025, 6, 5, 3, 0, 0, 0, helsinki),
+			ok:    false,
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			until, ok := ts.quiet.Until(ts.now)
+			if ok != ts.ok || !until.Equal(ts.expected) {
+				t.Errorf("expected (%s, %t), got (%s, %t)", ts.expected, ts.ok, until, ok)
+			}
+		})
+	}
+}
+
+func TestNotificationUrgent(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name     string
+		notif    Notification
+		expected bool
+	}{
+		{
+			name:     "offline",
+			notif:    Notification{Type: NotificationTypeOffline},
+			expected: true,
+		},
+		{
+			name:     "expires within a day",
+			notif:    Notification{Type: NotificationTypeExpiration, Host: testHost("example.com", hosts.CertificateStatusHealthy, 20*time.Hour)},
+			expected: true,
+		},
+		{
+			name:     "expires within two days",
+			notif:    Notification{Type: NotificationTypeExpiration, Host: testHost("example.com", hosts.CertificateStatusHealthy, day+time.Hour)},
+			expected: false,
+		},
+		{
+			name:     "renewal",
+			notif:    Notification{Type: NotificationTypeRenewal, Host: testHost("example.com", hosts.CertificateStatusHealthy, 90*day)},
+			expected: false,
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			if urgent := ts.notif.Urgent(); urgent != ts.expected {
+				t.Errorf("expected %t, got %t", ts.expected, urgent)
+			}
+		})
+	}
+}
+
+func TestFormatReminderMsgTimeZone(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	expires := time.Now().Add(3*24*time.Hour + time.Hour).Truncate(time.Minute).UTC()
+	h := hosts.Host{
+		Hostname:    "example.com",
+		Certificate: hosts.CertificateInfo{ExpiresAt: &expires},
+	}
+	expected := "TLS certificate for example.com will expire in 3 days, on " +
+		expires.In(helsinki).Format("2006-01-02 15:04 MST")
+	if msg := formatReminderMsg(h, helsinki); msg != expected {
+		t.Errorf("expected %q, got %q", expected, msg)
+	}
+}
@@ -2,10 +2,14 @@ package notifications
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/lionpuro/neverexpire/db"
+	"github.com/lionpuro/neverexpire/hosts"
 	"github.com/lionpuro/neverexpire/logging"
+	"github.com/lionpuro/neverexpire/users"
 )
 
 type Repository struct {
@@ -29,12 +33,26 @@ func (r *Repository) AllByUser(ctx context.Context, uid string) ([]AppNotificati
 		n.read_at,
 		n.attempts,
 		n.deleted_after,
-		n.created_at
+		n.created_at,
+		COALESCE(
+			uh.acknowledged_at >= n.due AND uh.acknowledged_fingerprint = h.signature,
+			false
+		) AS acknowledged,
+		CASE
+			WHEN uh.snoozed_until > (now() at time zone 'utc')
+				AND uh.snoozed_at >= n.due
+				AND uh.snoozed_fingerprint = h.signature
+			THEN uh.snoozed_until
+		END AS snoozed_until
 	FROM notifications n
+	LEFT JOIN user_hosts uh
+		ON uh.host_id = n.host_id AND uh.user_id = n.user_id
+	LEFT JOIN hosts h
+		ON h.id = n.host_id
 	WHERE
 		n.user_id = $1
 		AND n.deleted_after > (now() at time zone 'utc')
-	ORDER BY created_at DESC`
+	ORDER BY n.created_at DESC`
 	rows, err := r.db.Query(ctx, sql, uid)
 	if err != nil {
 		return nil, err
@@ -73,8 +91,19 @@ func (r *Repository) Update(ctx context.Context, uid string, input []Notificatio
 	return tx.Commit(ctx)
 }
 
-func (r *Repository) Upsert(ctx context.Context, n Notification) error {
-	sql := `
+// Enqueue saves the notification along with its pending deliveries. It
+// returns the id of the notification.
+func (r *Repository) Enqueue(ctx context.Context, n Notification, deliveries []Delivery) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logging.DefaultLogger().Error("failed to roll back tx", "error", err.Error())
+		}
+	}()
+	q := `
 	INSERT INTO notifications (
 		user_id,
 		host_id,
@@ -82,23 +111,711 @@ func (r *Repository) Upsert(ctx context.Context, n Notification) error {
 		body,
 		due,
 		delivered_at,
-		attempts,
 		deleted_after
 	)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-	ON CONFLICT (user_id, host_id, due) DO UPDATE SET
-		delivered_at = EXCLUDED.delivered_at,
-		attempts     = EXCLUDED.attempts
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (user_id, host_id, notification_type, due) DO UPDATE SET
+		body = notifications.body
+	RETURNING id
 	`
-	_, err := r.db.Exec(ctx, sql,
+	var id int
+	err = tx.QueryRow(ctx, q,
 		n.UserID,
 		n.HostID,
 		n.Type,
 		n.Body,
 		n.Due,
 		n.DeliveredAt,
-		n.Attempts,
 		n.DeletedAfter,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	channelIDs := make([]int, len(deliveries))
+	digests := make([]bool, len(deliveries))
+	nextAttempts := make([]time.Time, len(deliveries))
+	for i, d := range deliveries {
+		channelIDs[i] = d.ChannelID
+		digests[i] = d.Digest
+		nextAttempts[i] = d.NextAttemptAt
+	}
+	q = `
+	INSERT INTO deliveries (notification_id, channel_id, digest, next_attempt_at)
+	SELECT $1, d.channel_id, d.digest, d.next_attempt_at
+	FROM unnest($2::int[], $3::boolean[], $4::timestamp[]) AS d(channel_id, digest, next_attempt_at)
+	ON CONFLICT (notification_id, channel_id) DO NOTHING`
+	if _, err := tx.Exec(ctx, q, id, channelIDs, digests, nextAttempts); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// Channels returns the channels of the user with their routing rules.
+func (r *Repository) Channels(ctx context.Context, userID string) ([]Channel, error) {
+	q := `
+	SELECT
+		id,
+		user_id,
+		name,
+		provider,
+		url,
+		COALESCE(secret, ''),
+		created_at,
+		consecutive_failures,
+		COALESCE(message_template, '')
+	FROM channels
+	WHERE user_id = $1
+	ORDER BY created_at, id`
+	rows, err := r.db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var channels []Channel
+	for rows.Next() {
+		var c Channel
+		err := rows.Scan(
+			&c.ID,
+			&c.UserID,
+			&c.Name,
+			&c.Provider,
+			&c.URL,
+			&c.Secret,
+			&c.CreatedAt,
+			&c.ConsecutiveFailures,
+			&c.MessageTemplate,
+		)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rules, err := r.rules(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range channels {
+		for _, rule := range rules {
+			if rule.ChannelID == c.ID {
+				channels[i].Rules = append(channels[i].Rules, rule)
+			}
+		}
+	}
+	return channels, nil
+}
+
+func (r *Repository) rules(ctx context.Context, userID string) ([]Rule, error) {
+	q := `
+	SELECT rr.id, rr.channel_id, rr.notification_types, rr.min_severity
+	FROM routing_rules rr
+	INNER JOIN channels c
+		ON c.id = rr.channel_id
+	WHERE c.user_id = $1
+	ORDER BY rr.id`
+	rows, err := r.db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var rules []Rule
+	for rows.Next() {
+		var rule Rule
+		var types []int
+		if err := rows.Scan(&rule.ID, &rule.ChannelID, &types, &rule.MinSeverity); err != nil {
+			return nil, err
+		}
+		for _, t := range types {
+			rule.Types = append(rule.Types, NotificationType(t))
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// CreateChannel creates the channel with a rule that routes every
+// notification to it.
+func (r *Repository) CreateChannel(ctx context.Context, c Channel) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logging.DefaultLogger().Error("failed to roll back tx", "error", err.Error())
+		}
+	}()
+	q := `
+	INSERT INTO channels (user_id, name, provider, url, secret)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+	RETURNING id`
+	var id int
+	if err := tx.QueryRow(ctx, q, c.UserID, c.Name, c.Provider, c.URL, c.Secret).Scan(&id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO routing_rules (channel_id) VALUES ($1)`, id); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit(ctx)
+}
+
+// UpdateChannel updates the name, URL and secret of the channel. Changing the
+// URL clears the failures of the channel. It returns pgx.ErrNoRows if the user
+// has no such channel.
+func (r *Repository) UpdateChannel(ctx context.Context, c Channel) error {
+	q := `
+	UPDATE channels
+	SET
+		name   = $3,
+		url    = $4,
+		secret = NULLIF($5, ''),
+		consecutive_failures = CASE WHEN url = $4 THEN consecutive_failures ELSE 0 END
+	WHERE id = $1 AND user_id = $2`
+	tag, err := r.db.Exec(ctx, q, c.ID, c.UserID, c.Name, c.URL, c.Secret)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// SetChannelTemplate saves the message template of the channel. An empty
+// template uses the template of the user. It returns pgx.ErrNoRows if the
+// user has no such channel.
+func (r *Repository) SetChannelTemplate(ctx context.Context, userID string, channelID int, tmpl string) error {
+	q := `
+	UPDATE channels
+	SET message_template = NULLIF($3, '')
+	WHERE id = $2 AND user_id = $1`
+	tag, err := r.db.Exec(ctx, q, userID, channelID, tmpl)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *Repository) DeleteChannel(ctx context.Context, id int, userID string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM channels WHERE id = $1 AND user_id = $2`, id, userID)
+	return err
+}
+
+// CreateRule adds a rule to a channel of the user. It returns pgx.ErrNoRows
+// if the user has no such channel.
+func (r *Repository) CreateRule(ctx context.Context, userID string, rule Rule) (int, error) {
+	q := `
+	INSERT INTO routing_rules (channel_id, notification_types, min_severity)
+	SELECT c.id, $3, $4
+	FROM channels c
+	WHERE c.id = $1 AND c.user_id = $2
+	RETURNING id`
+	types := make([]int, len(rule.Types))
+	for i, t := range rule.Types {
+		types[i] = int(t)
+	}
+	var id int
+	err := r.db.QueryRow(ctx, q, rule.ChannelID, userID, types, rule.MinSeverity).Scan(&id)
+	return id, err
+}
+
+func (r *Repository) DeleteRule(ctx context.Context, userID string, channelID, ruleID int) error {
+	q := `
+	DELETE FROM routing_rules rr
+	USING channels c
+	WHERE rr.id = $3
+		AND rr.channel_id = $2
+		AND c.id = rr.channel_id
+		AND c.user_id = $1`
+	_, err := r.db.Exec(ctx, q, userID, channelID, ruleID)
+	return err
+}
+
+const outboxColumns = `
+	d.id,
+	d.notification_id,
+	d.channel_id,
+	d.attempts,
+	d.next_attempt_at,
+	d.delivered_at,
+	d.failed_at,
+	d.suppressed_at,
+	COALESCE(d.error_message, ''),
+	d.digest,
+	n.user_id,
+	n.host_id,
+	n.notification_type,
+	n.body,
+	n.due,
+	n.deleted_after,
+	c.name,
+	c.provider,
+	c.url,
+	COALESCE(c.secret, ''),
+	CASE WHEN s.email_verified_at IS NOT NULL THEN COALESCE(s.email, '') ELSE '' END,
+	h.hostname,
+	h.port,
+	s.time_zone,
+	s.quiet_hours_start,
+	s.quiet_hours_end,
+	COALESCE(c.message_template, s.message_template, '')`
+
+// ClaimDeliveries returns up to limit deliveries that are due to be sent on
+// their own. The deliveries aren't returned again until lease has passed, so
+// that concurrent workers don't send them twice.
+func (r *Repository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]OutboxEntry, error) {
+	return r.claim(ctx, false, limit, lease)
+}
+
+// ClaimDigests returns up to limit deliveries that are due to be sent in
+// digests, like ClaimDeliveries.
+func (r *Repository) ClaimDigests(ctx context.Context, limit int, lease time.Duration) ([]OutboxEntry, error) {
+	return r.claim(ctx, true, limit, lease)
+}
+
+func (r *Repository) claim(ctx context.Context, digest bool, limit int, lease time.Duration) ([]OutboxEntry, error) {
+	q := `
+	WITH due AS (
+		SELECT id
+		FROM deliveries
+		WHERE delivered_at IS NULL
+			AND failed_at IS NULL
+			AND suppressed_at IS NULL
+			AND digest = $3
+			AND next_attempt_at <= (now() at time zone 'utc')
+		ORDER BY next_attempt_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	), claimed AS (
+		UPDATE deliveries d
+		SET next_attempt_at = (now() at time zone 'utc') + $2 * interval '1 second'
+		FROM due
+		WHERE d.id = due.id
+		RETURNING d.*
+	)
+	SELECT ` + outboxColumns + `
+	FROM claimed d
+	INNER JOIN notifications n
+		ON n.id = d.notification_id
+	INNER JOIN channels c
+		ON c.id = d.channel_id
+	INNER JOIN settings s
+		ON s.user_id = n.user_id
+	INNER JOIN hosts h
+		ON h.id = n.host_id
+	ORDER BY d.next_attempt_at, d.id`
+	rows, err := r.db.Query(ctx, q, limit, int(lease.Seconds()), digest)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanOutbox(rows)
+}
+
+// FailedDeliveries returns the deliveries of the user that were given up
+// after running out of attempts, latest first.
+func (r *Repository) FailedDeliveries(ctx context.Context, userID string) ([]OutboxEntry, error) {
+	q := `
+	SELECT ` + outboxColumns + `
+	FROM deliveries d
+	INNER JOIN notifications n
+		ON n.id = d.notification_id
+	INNER JOIN channels c
+		ON c.id = d.channel_id
+	INNER JOIN settings s
+		ON s.user_id = n.user_id
+	INNER JOIN hosts h
+		ON h.id = n.host_id
+	WHERE n.user_id = $1
+		AND d.failed_at IS NOT NULL
+		AND n.deleted_after > (now() at time zone 'utc')
+	ORDER BY d.failed_at DESC, d.id DESC`
+	rows, err := r.db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanOutbox(rows)
+}
+
+func scanOutbox(rows pgx.Rows) ([]OutboxEntry, error) {
+	var entries []OutboxEntry
+	for rows.Next() {
+		var e OutboxEntry
+		var host hosts.Host
+		var timeZone string
+		var quietStart, quietEnd *int
+		err := rows.Scan(
+			&e.ID,
+			&e.NotificationID,
+			&e.ChannelID,
+			&e.Attempts,
+			&e.NextAttemptAt,
+			&e.DeliveredAt,
+			&e.FailedAt,
+			&e.SuppressedAt,
+			&e.Error,
+			&e.Digest,
+			&e.Notification.UserID,
+			&e.Notification.HostID,
+			&e.Notification.Type,
+			&e.Notification.Body,
+			&e.Notification.Due,
+			&e.Notification.DeletedAfter,
+			&e.Channel.Name,
+			&e.Channel.Provider,
+			&e.Channel.URL,
+			&e.Channel.Secret,
+			&e.Notification.Email,
+			&host.Hostname,
+			&host.Port,
+			&timeZone,
+			&quietStart,
+			&quietEnd,
+			&e.MessageTemplate,
+		)
+		if err != nil {
+			return nil, err
+		}
+		e.Location = users.LoadLocation(timeZone)
+		e.QuietHours = quietHours(quietStart, quietEnd)
+		e.Notification.ID = e.NotificationID
+		host.ID = e.Notification.HostID
+		e.Notification.Host = &host
+		e.Channel.ID = e.ChannelID
+		e.Channel.UserID = e.Notification.UserID
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// UpdateDelivery saves the result of an attempt to send the delivery. The
+// notification is marked as delivered once every channel has received it or
+// held it back.
+func (r *Repository) UpdateDelivery(ctx context.Context, d Delivery) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logging.DefaultLogger().Error("failed to roll back tx", "error", err.Error())
+		}
+	}()
+	q := `
+	UPDATE deliveries
+	SET
+		attempts        = $2,
+		next_attempt_at = $3,
+		delivered_at    = $4,
+		failed_at       = $5,
+		suppressed_at   = $6,
+		error_message   = NULLIF($7, ''),
+		updated_at      = (now() at time zone 'utc')
+	WHERE id = $1`
+	_, err = tx.Exec(ctx, q, d.ID, d.Attempts, d.NextAttemptAt, d.DeliveredAt, d.FailedAt, d.SuppressedAt, d.Error)
+	if err != nil {
+		return err
+	}
+	done := d.DeliveredAt
+	if done == nil {
+		done = d.SuppressedAt
+	}
+	if done != nil {
+		q = `
+		UPDATE notifications n
+		SET delivered_at = $2
+		WHERE n.id = $1
+			AND n.delivered_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM deliveries d
+				WHERE d.notification_id = n.id
+					AND d.delivered_at IS NULL
+					AND d.suppressed_at IS NULL
+			)`
+		if _, err := tx.Exec(ctx, q, d.NotificationID, done); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// LogAttempt saves the attempt in the delivery log of the channel and updates
+// the count of failed attempts in a row.
+func (r *Repository) LogAttempt(ctx context.Context, a Attempt) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logging.DefaultLogger().Error("failed to roll back tx", "error", err.Error())
+		}
+	}()
+	q := `
+	INSERT INTO delivery_attempts (
+		delivery_id,
+		channel_id,
+		status_code,
+		response_body,
+		latency_ms,
+		error_message
+	)
+	VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), $5, NULLIF($6, ''))`
+	_, err = tx.Exec(ctx, q,
+		a.DeliveryID,
+		a.ChannelID,
+		a.StatusCode,
+		a.Response,
+		a.Latency.Milliseconds(),
+		a.Error,
 	)
+	if err != nil {
+		return err
+	}
+	q = `
+	UPDATE channels
+	SET consecutive_failures = CASE WHEN $2 THEN consecutive_failures + 1 ELSE 0 END
+	WHERE id = $1`
+	if _, err := tx.Exec(ctx, q, a.ChannelID, a.Error != ""); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Attempts returns the latest delivery attempts to a channel of the user,
+// latest first.
+func (r *Repository) Attempts(ctx context.Context, userID string, channelID, limit int) ([]Attempt, error) {
+	q := `
+	SELECT
+		a.id,
+		a.delivery_id,
+		a.channel_id,
+		COALESCE(a.status_code, 0),
+		COALESCE(a.response_body, ''),
+		a.latency_ms,
+		COALESCE(a.error_message, ''),
+		a.created_at
+	FROM delivery_attempts a
+	INNER JOIN channels c
+		ON c.id = a.channel_id
+	WHERE a.channel_id = $1 AND c.user_id = $2
+	ORDER BY a.created_at DESC, a.id DESC
+	LIMIT $3`
+	rows, err := r.db.Query(ctx, q, channelID, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var attempts []Attempt
+	for rows.Next() {
+		var a Attempt
+		var latency int64
+		err := rows.Scan(
+			&a.ID,
+			&a.DeliveryID,
+			&a.ChannelID,
+			&a.StatusCode,
+			&a.Response,
+			&latency,
+			&a.Error,
+			&a.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		a.Latency = time.Duration(latency) * time.Millisecond
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// Schedule returns the time zone, the quiet hours and the digest schedule of
+// the user.
+func (r *Repository) Schedule(ctx context.Context, userID string) (Schedule, error) {
+	q := `
+	SELECT
+		time_zone,
+		quiet_hours_start,
+		quiet_hours_end,
+		digest_frequency,
+		digest_hour,
+		digest_weekday
+	FROM settings
+	WHERE user_id = $1`
+	var (
+		s                    Schedule
+		timeZone             string
+		quietStart, quietEnd *int
+	)
+	err := r.db.QueryRow(ctx, q, userID).Scan(
+		&timeZone,
+		&quietStart,
+		&quietEnd,
+		&s.Digest.Frequency,
+		&s.Digest.Hour,
+		&s.Digest.Weekday,
+	)
+	if err != nil {
+		return Schedule{}, err
+	}
+	s.Location = users.LoadLocation(timeZone)
+	s.QuietHours = quietHours(quietStart, quietEnd)
+	return s, nil
+}
+
+// SetDigestSchedule saves the digest schedule of the user. Reminders waiting
+// for the next digest are moved to the new schedule, or sent right away if
+// digests were turned off.
+func (r *Repository) SetDigestSchedule(ctx context.Context, userID string, s DigestSchedule) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logging.DefaultLogger().Error("failed to roll back tx", "error", err.Error())
+		}
+	}()
+	q := `
+	UPDATE settings
+	SET
+		digest_frequency = $2,
+		digest_hour      = $3,
+		digest_weekday   = $4
+	WHERE user_id = $1
+	RETURNING time_zone`
+	var timeZone string
+	err = tx.QueryRow(ctx, q, userID, s.Frequency, s.Hour, s.Weekday).Scan(&timeZone)
+	if err != nil {
+		return err
+	}
+	if err := rescheduleDigests(ctx, tx, userID, s, users.LoadLocation(timeZone)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetQuietHours saves the time zone and the quiet hours of the user.
+// Reminders waiting for the next digest are moved to the digest hour in the
+// new time zone.
+func (r *Repository) SetQuietHours(ctx context.Context, userID string, loc *time.Location, quiet QuietHours) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logging.DefaultLogger().Error("failed to roll back tx", "error", err.Error())
+		}
+	}()
+	var start, end *int
+	if quiet.Enabled {
+		start, end = &quiet.Start, &quiet.End
+	}
+	q := `
+	UPDATE settings
+	SET
+		time_zone         = $2,
+		quiet_hours_start = $3,
+		quiet_hours_end   = $4
+	WHERE user_id = $1
+	RETURNING digest_frequency, digest_hour, digest_weekday`
+	var digest DigestSchedule
+	err = tx.QueryRow(ctx, q, userID, loc.String(), start, end).Scan(
+		&digest.Frequency,
+		&digest.Hour,
+		&digest.Weekday,
+	)
+	if err != nil {
+		return err
+	}
+	if err := rescheduleDigests(ctx, tx, userID, digest, loc); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// rescheduleDigests moves the reminders of the user waiting for the next
+// digest to the schedule, or sends them right away if digests are off.
+func rescheduleDigests(ctx context.Context, tx pgx.Tx, userID string, s DigestSchedule, loc *time.Location) error {
+	next := time.Now().UTC()
+	if s.Enabled() {
+		next = s.Next(next.In(loc)).UTC()
+	}
+	q := `
+	UPDATE deliveries d
+	SET
+		digest          = $2,
+		next_attempt_at = $3
+	FROM notifications n
+	WHERE n.id = d.notification_id
+		AND n.user_id = $1
+		AND d.digest
+		AND d.attempts = 0
+		AND d.delivered_at IS NULL
+		AND d.failed_at IS NULL`
+	_, err := tx.Exec(ctx, q, userID, s.Enabled(), next)
 	return err
 }
+
+// MessageTemplate returns the message template of the user, or an empty
+// string if the user uses the default messages.
+func (r *Repository) MessageTemplate(ctx context.Context, userID string) (string, error) {
+	q := `
+	SELECT COALESCE(message_template, '')
+	FROM settings
+	WHERE user_id = $1`
+	var tmpl string
+	err := r.db.QueryRow(ctx, q, userID).Scan(&tmpl)
+	return tmpl, err
+}
+
+// SetMessageTemplate saves the message template of the user. An empty
+// template restores the default messages.
+func (r *Repository) SetMessageTemplate(ctx context.Context, userID, tmpl string) error {
+	q := `
+	UPDATE settings
+	SET message_template = NULLIF($2, '')
+	WHERE user_id = $1`
+	tag, err := r.db.Exec(ctx, q, userID, tmpl)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ResendDelivery schedules a failed delivery of the user to be sent again
+// with a new set of attempts. It returns pgx.ErrNoRows if the user has no
+// such failed delivery.
+func (r *Repository) ResendDelivery(ctx context.Context, userID string, id int) error {
+	q := `
+	UPDATE deliveries d
+	SET
+		attempts        = 0,
+		next_attempt_at = (now() at time zone 'utc'),
+		failed_at       = NULL,
+		error_message   = NULL,
+		updated_at      = (now() at time zone 'utc')
+	FROM notifications n
+	WHERE d.id = $1
+		AND n.id = d.notification_id
+		AND n.user_id = $2
+		AND d.failed_at IS NOT NULL`
+	tag, err := r.db.Exec(ctx, q, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxDeliveryAttempts is how many times a notification is sent to a
+	// channel before the delivery is given up.
+	MaxDeliveryAttempts = 8
+	// retryBaseDelay is the delay before the first retry. It doubles after
+	// every failed attempt up to retryMaxDelay.
+	retryBaseDelay = time.Minute
+	retryMaxDelay  = 6 * time.Hour
+	// maxRetryAfter caps the delay a provider can ask for with Retry-After.
+	maxRetryAfter = 24 * time.Hour
+)
+
+// retryAfterError is returned when the provider rate limited the request and
+// asked to wait before retrying.
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %s)", e.err, e.after)
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+// backoff returns the delay before retrying a delivery that has failed
+// attempts times. The delay grows exponentially and half of it is random so
+// that deliveries failing at the same time are spread out.
+func backoff(attempts int) time.Duration {
+	d := retryMaxDelay
+	if attempts < 1 {
+		attempts = 1
+	}
+	if shift := attempts - 1; shift < 16 {
+		d = min(retryBaseDelay<<shift, retryMaxDelay)
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// retryDelay returns the delay before retrying a delivery that failed with
+// err. A Retry-After asked for by the provider is honored if it's longer than
+// the backoff.
+func retryDelay(attempts int, err error) time.Duration {
+	d := backoff(attempts)
+	var rerr *retryAfterError
+	if errors.As(err, &rerr) && rerr.after > d {
+		d = min(rerr.after, maxRetryAfter)
+	}
+	return d
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either
+// a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
+
+// checkRateLimit wraps err in a retryAfterError if the response is a 429 with
+// a Retry-After header.
+func checkRateLimit(res *http.Response, err error) error {
+	if err == nil || res.StatusCode != http.StatusTooManyRequests {
+		return err
+	}
+	after, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return err
+	}
+	return &retryAfterError{err: err, after: after}
+}
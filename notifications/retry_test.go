@@ -0,0 +1,90 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lionpuro/neverexpire/logging"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		min      time.Duration
+		max      time.Duration
+	}{
+		{attempts: 1, min: 30 * time.Second, max: time.Minute},
+		{attempts: 2, min: time.Minute, max: 2 * time.Minute},
+		{attempts: 4, min: 4 * time.Minute, max: 8 * time.Minute},
+		{attempts: 10, min: 3 * time.Hour, max: 6 * time.Hour},
+		{attempts: 100, min: 3 * time.Hour, max: 6 * time.Hour},
+	}
+	for _, ts := range tests {
+		t.Run(fmt.Sprintf("attempt %d", ts.attempts), func(t *testing.T) {
+			for range 50 {
+				d := backoff(ts.attempts)
+				if d < ts.min || d > ts.max {
+					t.Fatalf("expected backoff between %s and %s, got %s", ts.min, ts.max, d)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "seconds", value: "120", expected: 2 * time.Minute, ok: true},
+		{name: "date", value: "Sun, 01 Jun 2025 12:05:00 GMT", expected: 5 * time.Minute, ok: true},
+		{name: "past date", value: "Sun, 01 Jun 2025 11:00:00 GMT", expected: 0, ok: true},
+		{name: "negative", value: "-1", ok: false},
+		{name: "empty", value: "", ok: false},
+		{name: "invalid", value: "soon", ok: false},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			d, ok := parseRetryAfter(ts.value, now)
+			if ok != ts.ok || d != ts.expected {
+				t.Errorf("expected (%s, %t), got (%s, %t)", ts.expected, ts.ok, d, ok)
+			}
+		})
+	}
+}
+
+func TestSendNotificationRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	notif := Notification{
+		Endpoint: srv.URL,
+		Body:     "example.com expires in 1 day",
+		Provider: GenericProvider,
+		Secret:   "secret",
+	}
+	_, err := sendNotification(logging.DefaultLogger(), srv.Client(), notif, EventTest)
+	var rerr *retryAfterError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected a retry after error, got %v", err)
+	}
+	if rerr.after != time.Hour {
+		t.Errorf("expected retry after 1h, got %s", rerr.after)
+	}
+	if d := retryDelay(1, err); d != time.Hour {
+		t.Errorf("expected the retry to wait for 1h, got %s", d)
+	}
+	if d := retryDelay(1, errors.New("response status: 500")); d > time.Minute {
+		t.Errorf("expected the backoff delay, got %s", d)
+	}
+}
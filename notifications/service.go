@@ -3,6 +3,8 @@ package notifications
 import (
 	"context"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type Service struct {
@@ -25,8 +27,137 @@ func (s *Service) Update(uid string, input []NotificationUpdate) error {
 	return s.repo.Update(ctx, uid, input)
 }
 
-func (s *Service) Upsert(ctx context.Context, n Notification) error {
+func (s *Service) Enqueue(ctx context.Context, n Notification, deliveries []Delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.repo.Enqueue(ctx, n, deliveries)
+}
+
+func (s *Service) Channels(ctx context.Context, userID string) ([]Channel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.repo.Channels(ctx, userID)
+}
+
+// Channel returns the channel of the user with the id, or pgx.ErrNoRows if
+// there is none.
+func (s *Service) Channel(ctx context.Context, id int, userID string) (Channel, error) {
+	channels, err := s.Channels(ctx, userID)
+	if err != nil {
+		return Channel{}, err
+	}
+	for _, c := range channels {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return Channel{}, pgx.ErrNoRows
+}
+
+func (s *Service) CreateChannel(c Channel) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.CreateChannel(ctx, c)
+}
+
+func (s *Service) UpdateChannel(c Channel) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.UpdateChannel(ctx, c)
+}
+
+func (s *Service) DeleteChannel(id int, userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.DeleteChannel(ctx, id, userID)
+}
+
+func (s *Service) CreateRule(userID string, rule Rule) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.CreateRule(ctx, userID, rule)
+}
+
+func (s *Service) DeleteRule(userID string, channelID, ruleID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.DeleteRule(ctx, userID, channelID, ruleID)
+}
+
+func (s *Service) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]OutboxEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.repo.ClaimDeliveries(ctx, limit, lease)
+}
+
+func (s *Service) ClaimDigests(ctx context.Context, limit int, lease time.Duration) ([]OutboxEntry, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	return s.repo.Upsert(ctx, n)
+	return s.repo.ClaimDigests(ctx, limit, lease)
+}
+
+func (s *Service) UpdateDelivery(ctx context.Context, d Delivery) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.repo.UpdateDelivery(ctx, d)
+}
+
+func (s *Service) LogAttempt(ctx context.Context, a Attempt) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.repo.LogAttempt(ctx, a)
+}
+
+func (s *Service) Attempts(ctx context.Context, userID string, channelID, limit int) ([]Attempt, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.repo.Attempts(ctx, userID, channelID, limit)
+}
+
+func (s *Service) FailedDeliveries(ctx context.Context, userID string) ([]OutboxEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.repo.FailedDeliveries(ctx, userID)
+}
+
+func (s *Service) Schedule(ctx context.Context, userID string) (Schedule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.repo.Schedule(ctx, userID)
+}
+
+func (s *Service) SetDigestSchedule(userID string, schedule DigestSchedule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.SetDigestSchedule(ctx, userID, schedule)
+}
+
+func (s *Service) SetQuietHours(userID string, loc *time.Location, quiet QuietHours) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.SetQuietHours(ctx, userID, loc, quiet)
+}
+
+func (s *Service) MessageTemplate(ctx context.Context, userID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return s.repo.MessageTemplate(ctx, userID)
+}
+
+func (s *Service) SetMessageTemplate(userID, tmpl string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.SetMessageTemplate(ctx, userID, tmpl)
+}
+
+func (s *Service) SetChannelTemplate(userID string, channelID int, tmpl string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.SetChannelTemplate(ctx, userID, channelID, tmpl)
+}
+
+func (s *Service) ResendDelivery(userID string, id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.ResendDelivery(ctx, userID, id)
 }
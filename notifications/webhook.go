@@ -0,0 +1,127 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+// Headers of requests sent to generic webhooks. The signature is the hex
+// encoded HMAC-SHA256 of the timestamp, a dot and the request body, keyed with
+// the webhook secret of the user, prefixed with "sha256=".
+const (
+	SignatureHeader = "X-Neverexpire-Signature"
+	TimestampHeader = "X-Neverexpire-Timestamp"
+)
+
+// EventTest is the event of test notifications sent when a webhook is added.
+const EventTest = "test"
+
+// WebhookPayload is the body of the requests sent to generic webhooks.
+type WebhookPayload struct {
+	ID      int          `json:"id"`
+	Event   string       `json:"event"`
+	Message string       `json:"message"`
+	Host    *WebhookHost `json:"host"`
+	// Hosts are the hosts of a digest.
+	Hosts     []WebhookHost `json:"hosts,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+type WebhookHost struct {
+	Hostname  string     `json:"hostname"`
+	Port      int        `json:"port"`
+	Protocol  string     `json:"protocol"`
+	Status    string     `json:"status"`
+	Issuer    string     `json:"issuer"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// NewWebhookSecret generates a secret for signing generic webhook requests.
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature of a generic webhook request.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// generic posts a signed JSON payload to any HTTPS URL.
+type generic struct{}
+
+func (generic) ValidateURL(url string) bool {
+	return isHTTPS(url)
+}
+
+func (generic) NewRequest(n Notification, event string) (*http.Request, error) {
+	return newWebhookRequest(n, event, time.Now().UTC())
+}
+
+func (generic) CheckResponse(res *http.Response) error {
+	return checkStatus(res)
+}
+
+func newWebhookPayload(n Notification, event string, now time.Time) WebhookPayload {
+	payload := WebhookPayload{
+		ID:        n.ID,
+		Event:     event,
+		Message:   n.Body,
+		Timestamp: now,
+	}
+	if h := n.Host; h != nil {
+		host := newWebhookHost(*h)
+		payload.Host = &host
+	}
+	if d := n.Digest; d != nil {
+		for _, g := range d.Groups() {
+			for _, r := range g.Rows {
+				payload.Hosts = append(payload.Hosts, newWebhookHost(r.Host))
+			}
+		}
+	}
+	return payload
+}
+
+func newWebhookHost(h hosts.Host) WebhookHost {
+	return WebhookHost{
+		Hostname:  h.Hostname,
+		Port:      h.Port,
+		Protocol:  h.Protocol.String(),
+		Status:    h.Certificate.Status.String(),
+		Issuer:    h.Certificate.IssuedBy,
+		ExpiresAt: h.Certificate.ExpiresAt,
+	}
+}
+
+func newWebhookRequest(n Notification, event string, now time.Time) (*http.Request, error) {
+	body, err := json.Marshal(newWebhookPayload(n, event, now))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, n.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	ts := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(SignatureHeader, Sign(n.Secret, ts, body))
+	return req, nil
+}
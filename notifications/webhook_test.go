@@ -0,0 +1,100 @@
+package notifications
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lionpuro/neverexpire/hosts"
+	"github.com/lionpuro/neverexpire/logging"
+)
+
+func TestSendGenericWebhook(t *testing.T) {
+	secret, err := NewWebhookSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	expires := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	received := make(chan WebhookPayload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read body: %v", err)
+			return
+		}
+		ts, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		if err != nil {
+			t.Errorf("invalid timestamp header: %v", err)
+		}
+		if sig := r.Header.Get(SignatureHeader); sig != Sign(secret, ts, body) {
+			t.Errorf("signature %q doesn't match the body", sig)
+		}
+		var payload WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		received <- payload
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	notif := Notification{
+		ID:       42,
+		Endpoint: srv.URL,
+		Body:     "example.com expires in 1 day",
+		Provider: GenericProvider,
+		Secret:   secret,
+		Host: &hosts.Host{
+			Hostname: "example.com",
+			Port:     443,
+			Certificate: hosts.CertificateInfo{
+				Status:    hosts.CertificateStatusHealthy,
+				IssuedBy:  "Test CA",
+				ExpiresAt: &expires,
+			},
+		},
+	}
+	if _, err := sendNotification(logging.DefaultLogger(), srv.Client(), notif, NotificationTypeExpiration.String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	payload := <-received
+	if payload.ID != 42 || payload.Event != "expiration" || payload.Message != notif.Body {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+	if payload.Host == nil || payload.Host.Hostname != "example.com" || !payload.Host.ExpiresAt.Equal(expires) {
+		t.Errorf("unexpected host in payload: %+v", payload.Host)
+	}
+}
+
+func TestSendNotificationAttempt(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, strings.Repeat("invalid payload ", 200))
+	}))
+	defer srv.Close()
+
+	notif := Notification{
+		Endpoint: srv.URL,
+		Body:     "example.com expires in 1 day",
+		Provider: GenericProvider,
+		Secret:   "secret",
+	}
+	attempt, err := sendNotification(logging.DefaultLogger(), srv.Client(), notif, EventTest)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "invalid payload") {
+		t.Errorf("expected the error to include the response, got %v", err)
+	}
+	if attempt.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, attempt.StatusCode)
+	}
+	if len(attempt.Response) != maxLoggedResponse {
+		t.Errorf("expected the response to be truncated to %d bytes, got %d", maxLoggedResponse, len(attempt.Response))
+	}
+}
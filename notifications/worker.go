@@ -3,40 +3,36 @@ package notifications
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/lionpuro/neverexpire/db"
 	"github.com/lionpuro/neverexpire/hosts"
 	"github.com/lionpuro/neverexpire/logging"
 )
 
-func avatarURL() string {
-	if url := os.Getenv("WEBHOOK_AVATAR_URL"); url != "" {
-		return url
-	}
-	if os.Getenv("APP_ENV") == "production" {
-		return "https://neverexpire.lionpuro.com/assets/images/webhook-avatar.png"
-	}
-	return ""
-}
-
 type Worker struct {
 	interval      time.Duration
 	client        *http.Client
+	mailer        *Mailer
 	notifications *Service
 	hosts         *hosts.Service
-	log           logging.Logger
+	// appURL is the public URL of the app, used in links in messages.
+	appURL string
+	log    logging.Logger
 }
 
 func NewWorker(
 	interval time.Duration,
 	ns *Service,
 	hs *hosts.Service,
+	mailer *Mailer,
+	appURL string,
 	logger logging.Logger,
 ) *Worker {
 	return &Worker{
@@ -44,12 +40,26 @@ func NewWorker(
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		mailer:        mailer,
 		notifications: ns,
 		hosts:         hs,
+		appURL:        appURL,
 		log:           logger,
 	}
 }
 
+const (
+	// deliveryBatchSize is how many deliveries are claimed from the outbox at
+	// a time.
+	deliveryBatchSize = 100
+	// deliveryConcurrency is how many deliveries are sent at the same time.
+	deliveryConcurrency = 8
+	// deliveryLease is how long claimed deliveries are hidden from other
+	// workers. If the worker stops before saving the result, the deliveries
+	// are retried after the lease expires.
+	deliveryLease = 5 * time.Minute
+)
+
 func (w *Worker) Start(ctx context.Context) {
 	t := time.NewTicker(w.interval)
 	defer t.Stop()
@@ -60,39 +70,340 @@ func (w *Worker) Start(ctx context.Context) {
 			if err := w.NotifyExpiring(ctx); err != nil {
 				w.log.Error("failed to process notifications", "error", err.Error())
 			}
+			if err := w.NotifyRenewed(ctx); err != nil {
+				w.log.Error("failed to process renewal notifications", "error", err.Error())
+			}
+			if err := w.NotifyFailing(ctx); err != nil {
+				w.log.Error("failed to process failure notifications", "error", err.Error())
+			}
+			if err := w.NotifyRecovered(ctx); err != nil {
+				w.log.Error("failed to process recovery notifications", "error", err.Error())
+			}
+			if err := w.Deliver(ctx); err != nil {
+				w.log.Error("failed to deliver notifications", "error", err.Error())
+			}
+			if err := w.DeliverDigests(ctx); err != nil {
+				w.log.Error("failed to deliver digests", "error", err.Error())
+			}
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func (w *Worker) send(notif Notification) error {
-	return sendNotification(w.log, w.client, notif.Endpoint, notif.Body)
+// send delivers the notification to the channel.
+func (w *Worker) send(ch Channel, notif Notification) (Attempt, error) {
+	if ch.Provider == EmailProvider {
+		if w.mailer == nil || notif.Email == "" {
+			return Attempt{}, fmt.Errorf("no verified email address")
+		}
+		start := time.Now()
+		err := w.mailer.SendNotification(notif)
+		return Attempt{Latency: time.Since(start)}, err
+	}
+	notif.Endpoint = ch.URL
+	notif.Provider = ch.Provider
+	notif.Secret = ch.Secret
+	event := notif.Type.String()
+	if notif.Digest != nil {
+		event = EventDigest
+	}
+	return sendNotification(w.log, w.client, notif, event)
+}
+
+// enqueue saves the notifications shown in the app and adds them to the
+// outbox of every channel they are routed to. Expiry reminders of users who
+// have enabled digests wait for the next digest. Notifications that aren't
+// routed to any channel are saved as delivered.
+func (w *Worker) enqueue(ctx context.Context, notifs []Notification) {
+	type recipient struct {
+		channels []Channel
+		schedule Schedule
+	}
+	recipients := make(map[string]recipient)
+	now := time.Now().UTC()
+	for _, notif := range notifs {
+		rcpt, ok := recipients[notif.UserID]
+		if !ok {
+			channels, err := w.notifications.Channels(ctx, notif.UserID)
+			if err != nil {
+				w.log.Error("failed to retrieve channels", "error", err.Error())
+				continue
+			}
+			schedule, err := w.notifications.Schedule(ctx, notif.UserID)
+			if err != nil {
+				w.log.Error("failed to retrieve schedule", "error", err.Error())
+				continue
+			}
+			rcpt = recipient{channels: channels, schedule: schedule}
+			recipients[notif.UserID] = rcpt
+		}
+		var deliveries []Delivery
+		for _, ch := range rcpt.channels {
+			// email channels are skipped until the user has a verified address
+			if ch.Provider == EmailProvider && (notif.Email == "" || w.mailer == nil) {
+				continue
+			}
+			if !ch.Matches(notif) {
+				continue
+			}
+			d := Delivery{ChannelID: ch.ID, NextAttemptAt: now}
+			digest := rcpt.schedule.Digest
+			if notif.Type == NotificationTypeExpiration && digest.Enabled() && digestsProvider(ch.Provider) {
+				d.Digest = true
+				d.NextAttemptAt = digest.Next(now.In(rcpt.schedule.Location)).UTC()
+			}
+			deliveries = append(deliveries, d)
+		}
+		if len(deliveries) == 0 {
+			notif.DeliveredAt = &now
+		}
+		if _, err := w.notifications.Enqueue(ctx, notif, deliveries); err != nil {
+			w.log.Error("failed to enqueue notification", "error", err.Error())
+		}
+	}
+}
+
+// Deliver sends the deliveries in the outbox that are due. Failed deliveries
+// are retried with exponential backoff, and given up after
+// MaxDeliveryAttempts attempts. It returns once every claimed delivery has
+// been attempted.
+func (w *Worker) Deliver(ctx context.Context) error {
+	for {
+		entries, err := w.notifications.ClaimDeliveries(ctx, deliveryBatchSize, deliveryLease)
+		if err != nil {
+			return err
+		}
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, deliveryConcurrency)
+		for _, entry := range entries {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(e OutboxEntry) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				if err := w.deliver(ctx, e); err != nil {
+					w.log.Error("failed to save delivery", "error", err.Error())
+				}
+			}(entry)
+		}
+		wg.Wait()
+		if len(entries) < deliveryBatchSize || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// deliver makes one attempt to send the delivery and saves the result.
+// Deliveries that aren't urgent are held back until the quiet hours of the
+// user end, without using up an attempt. Deliveries about hosts the user has
+// snoozed or acknowledged aren't sent at all.
+func (w *Worker) deliver(ctx context.Context, e OutboxEntry) error {
+	notif := e.Notification
+	host, err := w.hosts.ByID(ctx, notif.HostID, notif.UserID)
+	if err != nil && !db.IsErrNoRows(err) {
+		// the delivery is retried when the lease expires
+		return err
+	}
+	d := e.Delivery
+	now := time.Now().UTC()
+	if err != nil {
+		d.Attempts++
+		d.Error = "host is no longer monitored"
+		d.FailedAt = &now
+		return w.notifications.UpdateDelivery(ctx, d)
+	}
+	notif.Host = &host
+	notif.Location = e.Location
+	if notif.Suppressed(now) {
+		d.SuppressedAt = &now
+		return w.notifications.UpdateDelivery(ctx, d)
+	}
+	if until, ok := e.QuietHours.Until(now.In(e.Location)); ok && !notif.Urgent() {
+		d.NextAttemptAt = until.UTC()
+		return w.notifications.UpdateDelivery(ctx, d)
+	}
+	notif.Body = w.message(e.MessageTemplate, notif)
+	d.Attempts++
+	d.Error = ""
+	attempt, err := w.send(e.Channel, notif)
+	attempt.DeliveryID = d.ID
+	attempt.ChannelID = e.ChannelID
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	if err := w.notifications.LogAttempt(ctx, attempt); err != nil {
+		w.log.Error("failed to log delivery attempt", "error", err.Error())
+	}
+	if err != nil {
+		w.log.Error(
+			"failed to send notification",
+			"channel", e.ChannelID,
+			"attempt", d.Attempts,
+			"error", err.Error(),
+		)
+	}
+	recordAttempt(&d, err, now, now.Add(retryDelay(d.Attempts, err)))
+	return w.notifications.UpdateDelivery(ctx, d)
+}
+
+// message returns the message of the notification rendered with the
+// template, or the default message if there's no template or it fails.
+func (w *Worker) message(tmpl string, notif Notification) string {
+	if tmpl == "" {
+		return notif.Body
+	}
+	msg, err := renderMessage(tmpl, notif, w.appURL)
+	if err != nil {
+		w.log.Error("failed to render message template", "user", notif.UserID, "error", err.Error())
+		return notif.Body
+	}
+	return msg
+}
+
+// recordAttempt updates the delivery with the result of an attempt to send it
+// at now. A failed delivery is retried at next, or given up after
+// MaxDeliveryAttempts attempts.
+func recordAttempt(d *Delivery, err error, now, next time.Time) {
+	if err == nil {
+		d.DeliveredAt = &now
+		return
+	}
+	d.Error = err.Error()
+	if d.Attempts >= MaxDeliveryAttempts {
+		d.FailedAt = &now
+		return
+	}
+	d.NextAttemptAt = next
+}
+
+// maxDigestDeliveries is how many reminders are claimed for digests at a
+// time.
+const maxDigestDeliveries = 10000
+
+// DeliverDigests sends the reminders that are due to be sent in digests, as
+// one message per channel.
+func (w *Worker) DeliverDigests(ctx context.Context) error {
+	entries, err := w.notifications.ClaimDigests(ctx, maxDigestDeliveries, deliveryLease)
+	if err != nil {
+		return err
+	}
+	var channels []int
+	byChannel := make(map[int][]OutboxEntry)
+	for _, e := range entries {
+		if _, ok := byChannel[e.ChannelID]; !ok {
+			channels = append(channels, e.ChannelID)
+		}
+		byChannel[e.ChannelID] = append(byChannel[e.ChannelID], e)
+	}
+	monitored := make(map[string]map[int]hosts.Host)
+	for _, id := range channels {
+		if err := w.deliverDigest(ctx, byChannel[id], monitored); err != nil {
+			w.log.Error("failed to deliver digest", "channel", id, "error", err.Error())
+		}
+	}
+	return nil
 }
 
-func (w *Worker) notify(notif Notification) error {
-	// create a notification shown in the app even if the user hasn't configured
-	// a notification channel
-	if notif.Endpoint == "" {
-		if err := w.notifications.Upsert(context.Background(), notif); err != nil {
+// deliverDigest sends the reminders to a channel as a digest, leaving out
+// the ones about hosts the user has snoozed or acknowledged. monitored caches
+// the hosts of the users by id.
+func (w *Worker) deliverDigest(ctx context.Context, entries []OutboxEntry, monitored map[string]map[int]hosts.Host) error {
+	first := entries[0]
+	userID := first.Notification.UserID
+	userHosts, ok := monitored[userID]
+	if !ok {
+		all, err := w.hosts.AllByUser(ctx, userID)
+		if err != nil {
+			// the reminders are retried when the lease expires
 			return err
 		}
+		userHosts = make(map[int]hosts.Host, len(all))
+		for _, h := range all {
+			userHosts[h.ID] = h
+		}
+		monitored[userID] = userHosts
+	}
+	now := time.Now().UTC()
+	var pending []OutboxEntry
+	for _, e := range entries {
+		n := e.Notification
+		if h, ok := userHosts[n.HostID]; ok {
+			n.Host = &h
+		}
+		if !n.Suppressed(now) {
+			pending = append(pending, e)
+			continue
+		}
+		d := e.Delivery
+		d.SuppressedAt = &now
+		if err := w.notifications.UpdateDelivery(ctx, d); err != nil {
+			return err
+		}
+	}
+	if len(pending) == 0 {
 		return nil
 	}
-	notif.Attempts++
-	if err := w.send(notif); err != nil {
-		if err := w.notifications.Upsert(context.Background(), notif); err != nil {
+	entries = pending
+	first = entries[0]
+
+	digest := Digest{Location: first.Location}
+	seen := make(map[int]bool)
+	for _, e := range entries {
+		if h, ok := userHosts[e.Notification.HostID]; ok && !seen[h.ID] {
+			digest.Hosts = append(digest.Hosts, h)
+			seen[h.ID] = true
+		}
+	}
+
+	if len(digest.Hosts) == 0 {
+		for _, e := range entries {
+			d := e.Delivery
+			d.Attempts++
+			d.Error = "host is no longer monitored"
+			d.FailedAt = &now
+			if err := w.notifications.UpdateDelivery(ctx, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	notif := Notification{
+		UserID:   userID,
+		Type:     NotificationTypeExpiration,
+		Body:     digest.Summary(),
+		Email:    first.Notification.Email,
+		Location: first.Location,
+		Digest:   &digest,
+	}
+	attempt, err := w.send(first.Channel, notif)
+	attempt.DeliveryID = first.ID
+	attempt.ChannelID = first.ChannelID
+	if err != nil {
+		attempt.Error = err.Error()
+		w.log.Error("failed to send digest", "channel", first.ChannelID, "error", err.Error())
+	}
+	if err := w.notifications.LogAttempt(ctx, attempt); err != nil {
+		w.log.Error("failed to log delivery attempt", "error", err.Error())
+	}
+	// the reminders are retried together to keep them in the same digest
+	next := now.Add(retryDelay(first.Attempts+1, err))
+	for _, e := range entries {
+		d := e.Delivery
+		d.Attempts++
+		d.Error = ""
+		recordAttempt(&d, err, now, next)
+		if err := w.notifications.UpdateDelivery(ctx, d); err != nil {
 			return err
 		}
-		return fmt.Errorf("failed to send notification: %v", err)
 	}
-	t := time.Now().UTC()
-	notif.DeliveredAt = &t
-	return w.notifications.Upsert(context.Background(), notif)
+	return nil
 }
 
 func (w *Worker) NotifyExpiring(ctx context.Context) error {
-	records, err := w.hosts.Expiring(ctx)
+	records, err := w.hosts.Expiring(ctx, int(NotificationTypeExpiration))
 	if err != nil {
 		return err
 	}
@@ -104,20 +415,64 @@ func (w *Worker) NotifyExpiring(ctx context.Context) error {
 		}
 	}
 
-	var wg sync.WaitGroup
-	for _, notif := range notifs {
-		wg.Add(1)
-		go func(no Notification) {
-			defer wg.Done()
-			if err := w.notify(notif); err != nil {
-				w.log.Error("failed to notify user", "error", err.Error())
-			}
-		}(notif)
+	w.enqueue(ctx, notifs)
+	return nil
+}
+
+// NotifyRenewed notifies users about hosts that started serving a new
+// certificate.
+func (w *Worker) NotifyRenewed(ctx context.Context) error {
+	records, err := w.hosts.Renewed(ctx, int(NotificationTypeRenewal))
+	if err != nil {
+		return err
 	}
-	go func() {
-		wg.Wait()
-	}()
+	notifs := make([]Notification, len(records))
+	for i, rec := range records {
+		notifs[i] = newRenewal(rec)
+	}
+	w.enqueue(ctx, notifs)
+	return nil
+}
+
+// NotifyFailing notifies users about hosts that have been offline or have had
+// an invalid certificate for as many checks in a row as they have configured.
+func (w *Worker) NotifyFailing(ctx context.Context) error {
+	records, err := w.hosts.Failing(ctx, failureTypes)
+	if err != nil {
+		return err
+	}
+	w.enqueue(ctx, newFailures(records))
+	return nil
+}
 
+// newFailures returns the failure notifications the users haven't been sent
+// yet. A host that fails in a new way, such as an unreachable host that starts
+// serving an invalid certificate, is notified about again.
+func newFailures(records []hosts.FailingHost) []Notification {
+	var notifs []Notification
+	for _, rec := range records {
+		n := newFailure(rec)
+		if !slices.Contains(rec.Notified, int(n.Type)) {
+			notifs = append(notifs, n)
+		}
+	}
+	return notifs
+}
+
+// NotifyRecovered notifies users about hosts that became healthy again after
+// they were notified about a failure.
+func (w *Worker) NotifyRecovered(ctx context.Context) error {
+	records, err := w.hosts.Recovered(ctx, int(NotificationTypeRecovered), failureTypes)
+	if err != nil {
+		return err
+	}
+	var notifs []Notification
+	for _, rec := range records {
+		if rec.RecoveredAt != nil {
+			notifs = append(notifs, newRecovery(rec))
+		}
+	}
+	w.enqueue(ctx, notifs)
 	return nil
 }
 
@@ -126,23 +481,131 @@ func newReminder(record hosts.NotifiableHost) *Notification {
 	if exp == nil {
 		return nil
 	}
-	msg := formatReminderMsg(record.Host)
+	msg := formatReminderMsg(record.Host, record.Location())
 	diff := time.Duration(record.Threshold) * time.Second
 	n := &Notification{
-		Endpoint:     record.WebhookURL,
+		Email:        record.Email,
+		Host:         &record.Host,
 		UserID:       record.UserID,
 		HostID:       record.Host.ID,
 		Type:         NotificationTypeExpiration,
 		Body:         msg,
+		Location:     record.Location(),
 		Due:          record.Host.Certificate.ExpiresAt.Add(-diff),
 		DeliveredAt:  nil,
-		Attempts:     record.Attempts,
 		DeletedAfter: *exp,
 	}
 	return n
 }
 
-func formatReminderMsg(d hosts.Host) string {
+// renewalRetention is how long renewal notifications are kept.
+const renewalRetention = 14 * 24 * time.Hour
+
+func newRenewal(record hosts.RenewedHost) Notification {
+	return Notification{
+		Email:        record.Email,
+		Host:         &record.Host,
+		UserID:       record.UserID,
+		HostID:       record.Host.ID,
+		Type:         NotificationTypeRenewal,
+		Body:         formatRenewalMsg(record),
+		Location:     record.Location(),
+		Due:          record.Current.FirstSeen,
+		DeliveredAt:  nil,
+		DeletedAfter: record.Current.FirstSeen.Add(renewalRetention),
+	}
+}
+
+func formatRenewalMsg(record hosts.RenewedHost) string {
+	loc := record.Location()
+	return fmt.Sprintf(
+		"TLS certificate for %s was renewed: issued by %s, valid until %s (previously issued by %s, valid until %s)",
+		record.Host.Address(),
+		record.Current.Issuer,
+		formatDate(record.Current.NotAfter, loc),
+		record.Previous.Issuer,
+		formatDate(record.Previous.NotAfter, loc),
+	)
+}
+
+// statusRetention is how long failure and recovery notifications are kept.
+const statusRetention = 14 * 24 * time.Hour
+
+func newFailure(record hosts.FailingHost) Notification {
+	typ := NotificationTypeOffline
+	if record.Host.Certificate.Status == hosts.CertificateStatusInvalid {
+		typ = NotificationTypeInvalid
+	}
+	return Notification{
+		Email:        record.Email,
+		Host:         &record.Host,
+		UserID:       record.UserID,
+		HostID:       record.Host.ID,
+		Type:         typ,
+		Body:         formatFailureMsg(record),
+		Location:     record.Location(),
+		Due:          record.FailingSince,
+		DeliveredAt:  nil,
+		DeletedAfter: record.FailingSince.Add(statusRetention),
+	}
+}
+
+func newRecovery(record hosts.FailingHost) Notification {
+	return Notification{
+		Email:        record.Email,
+		Host:         &record.Host,
+		UserID:       record.UserID,
+		HostID:       record.Host.ID,
+		Type:         NotificationTypeRecovered,
+		Body:         formatRecoveryMsg(record),
+		Location:     record.Location(),
+		Due:          *record.RecoveredAt,
+		DeliveredAt:  nil,
+		DeletedAfter: record.RecoveredAt.Add(statusRetention),
+	}
+}
+
+func formatFailureMsg(record hosts.FailingHost) string {
+	h := record.Host
+	checks := "check"
+	if record.Failures != 1 {
+		checks = "checks"
+	}
+	if h.Certificate.Status == hosts.CertificateStatusInvalid {
+		reason := h.Certificate.Reason.Description()
+		if reason == "" && h.Certificate.Error != nil {
+			reason = h.Certificate.Error.Error()
+		}
+		return fmt.Sprintf(
+			"TLS certificate for %s is invalid (%d %s in a row): %s",
+			h.Address(),
+			record.Failures,
+			checks,
+			reason,
+		)
+	}
+	msg := fmt.Sprintf("%s is unreachable (%d %s in a row)", h.Address(), record.Failures, checks)
+	if err := h.Certificate.Error; err != nil {
+		msg += ": " + err.Error()
+	}
+	return msg
+}
+
+func formatRecoveryMsg(record hosts.FailingHost) string {
+	return fmt.Sprintf(
+		"%s is healthy again after failing checks since %s",
+		record.Host.Address(),
+		formatTime(record.FailingSince, record.Location()),
+	)
+}
+
+// formatReminderMsg formats the reminder about the certificate of the host,
+// with the expiry date in loc.
+func formatReminderMsg(d hosts.Host, loc *time.Location) string {
+	expires := formatTime(*d.Certificate.ExpiresAt, loc)
+	if d.Certificate.TimeLeft() == 0 {
+		return fmt.Sprintf("TLS certificate for %s expired on %s", d.Address(), expires)
+	}
 	hours := int(d.Certificate.TimeLeft().Hours())
 	count := hours / 24
 	unit := "days"
@@ -159,46 +622,46 @@ func formatReminderMsg(d hosts.Host) string {
 		}
 	}
 	msg := fmt.Sprintf(
-		"TLS certificate for %s will expire in %d %s",
-		d.Hostname,
+		"TLS certificate for %s will expire in %d %s, on %s",
+		d.Address(),
 		count,
 		unit,
+		expires,
 	)
 	return msg
 }
 
-func sendNotification(logger logging.Logger, client *http.Client, url, msg string) error {
-	body := map[string]string{}
-	if strings.Contains(url, "discord") {
-		body["content"] = msg
-		if url := avatarURL(); url != "" {
-			body["avatar_url"] = url
-		}
-	}
-	if strings.Contains(url, "slack") {
-		body["text"] = msg
-	}
-	buf, err := json.Marshal(body)
-	if err != nil {
-		return err
+// maxLoggedResponse is how much of the response body is kept in the delivery
+// log.
+const maxLoggedResponse = 1024
+
+// sendNotification sends the notification to its provider. The returned
+// attempt has the status, latency and beginning of the body of the response.
+func sendNotification(logger logging.Logger, client *http.Client, notif Notification, event string) (Attempt, error) {
+	var attempt Attempt
+	provider, ok := notif.Provider.Provider()
+	if !ok {
+		return attempt, fmt.Errorf("unsupported webhook provider: %q", notif.Provider)
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewReader(buf))
+	req, err := provider.NewRequest(notif, event)
 	if err != nil {
-		return err
+		return attempt, err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	start := time.Now()
 	res, err := client.Do(req)
+	attempt.Latency = time.Since(start)
 	if err != nil {
-		return err
+		return attempt, err
 	}
+	body := res.Body
 	defer func() {
-		if err := res.Body.Close(); err != nil {
+		if err := body.Close(); err != nil {
 			logger.Error("error closing webhook response body", "error", err.Error())
 		}
 	}()
-	code := res.StatusCode
-	if code < 200 || code > 299 {
-		return fmt.Errorf("response status: %s", res.Status)
-	}
-	return nil
+	b, _ := io.ReadAll(io.LimitReader(body, maxLoggedResponse))
+	res.Body = io.NopCloser(bytes.NewReader(b))
+	attempt.StatusCode = res.StatusCode
+	attempt.Response = strings.ToValidUTF8(string(b), "")
+	return attempt, checkRateLimit(res, provider.CheckResponse(res))
 }
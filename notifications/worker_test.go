@@ -0,0 +1,65 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lionpuro/neverexpire/hosts"
+)
+
+func TestNewFailures(t *testing.T) {
+	since := time.Date(2025, 6, 4, 10, 30, 0, 0, time.UTC)
+	failing := func(status hosts.CertificateStatus, notified ...NotificationType) hosts.FailingHost {
+		rec := hosts.FailingHost{
+			Host:         *testHost("example.com", status, 30*24*time.Hour),
+			Failures:     3,
+			FailingSince: since,
+		}
+		for _, typ := range notified {
+			rec.Notified = append(rec.Notified, int(typ))
+		}
+		return rec
+	}
+	tests := []struct {
+		name     string
+		record   hosts.FailingHost
+		expected []NotificationType
+	}{
+		{
+			name:     "offline",
+			record:   failing(hosts.CertificateStatusOffline),
+			expected: []NotificationType{NotificationTypeOffline},
+		},
+		{
+			name:     "still offline",
+			record:   failing(hosts.CertificateStatusOffline, NotificationTypeOffline),
+			expected: nil,
+		},
+		{
+			name:     "offline then invalid",
+			record:   failing(hosts.CertificateStatusInvalid, NotificationTypeOffline),
+			expected: []NotificationType{NotificationTypeInvalid},
+		},
+		{
+			name:     "still invalid",
+			record:   failing(hosts.CertificateStatusInvalid, NotificationTypeOffline, NotificationTypeInvalid),
+			expected: nil,
+		},
+	}
+	for _, ts := range tests {
+		t.Run(ts.name, func(t *testing.T) {
+			notifs := newFailures([]hosts.FailingHost{ts.record})
+			if len(notifs) != len(ts.expected) {
+				t.Fatalf("expected %d notifications, got %d", len(ts.expected), len(notifs))
+			}
+			for i, n := range notifs {
+				if n.Type != ts.expected[i] {
+					t.Errorf("expected type %v, got %v", ts.expected[i], n.Type)
+				}
+				if !n.Due.Equal(since) {
+					t.Errorf("expected due %s, got %s", since, n.Due)
+				}
+			}
+		})
+	}
+}
@@ -113,6 +113,8 @@ func insertTestData(conn *pgxpool.Pool) error {
 			sql := `
 			INSERT INTO hosts (
 				hostname,
+				port,
+				protocol,
 				dns_names,
 				ip_address,
 				issued_by,
@@ -121,22 +123,32 @@ func insertTestData(conn *pgxpool.Pool) error {
 				checked_at,
 				latency,
 				signature,
+				chain,
+				error_reason,
+				endpoints,
+				fingerprint_mismatch,
 				error_message
 			)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-			ON CONFLICT (hostname) DO UPDATE SET
-				dns_names      = EXCLUDED.dns_names,
-				ip_address     = EXCLUDED.ip_address,
-				issued_by      = EXCLUDED.issued_by,
-				status         = EXCLUDED.status,
-				expires_at     = EXCLUDED.expires_at,
-				checked_at     = EXCLUDED.checked_at,
-				latency        = EXCLUDED.latency,
-				signature      = EXCLUDED.signature,
-				error_message  = EXCLUDED.error_message
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE($12, '[]'::jsonb), $13, COALESCE($14, '[]'::jsonb), $15, $16)
+			ON CONFLICT (hostname, port, protocol) DO UPDATE SET
+				dns_names            = EXCLUDED.dns_names,
+				ip_address           = EXCLUDED.ip_address,
+				issued_by            = EXCLUDED.issued_by,
+				status               = EXCLUDED.status,
+				expires_at           = EXCLUDED.expires_at,
+				checked_at           = EXCLUDED.checked_at,
+				latency              = EXCLUDED.latency,
+				signature            = EXCLUDED.signature,
+				chain                = EXCLUDED.chain,
+				error_reason         = EXCLUDED.error_reason,
+				endpoints            = EXCLUDED.endpoints,
+				fingerprint_mismatch = EXCLUDED.fingerprint_mismatch,
+				error_message        = EXCLUDED.error_message
 			RETURNING id`
 			err := tx.QueryRow(ctx, sql,
 				h.Hostname,
+				h.Port,
+				h.Protocol,
 				h.Certificate.DNSNames,
 				h.Certificate.IP,
 				h.Certificate.IssuedBy,
@@ -145,6 +157,10 @@ func insertTestData(conn *pgxpool.Pool) error {
 				h.Certificate.CheckedAt,
 				h.Certificate.Latency,
 				h.Certificate.Signature,
+				h.Certificate.Chain,
+				h.Certificate.Reason,
+				h.Certificate.Endpoints,
+				h.Certificate.FingerprintMismatch,
 				errStr,
 			).Scan(&id)
 			if err != nil {
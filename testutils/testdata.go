@@ -3,6 +3,7 @@ package testutils
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
@@ -29,6 +30,10 @@ func NewTestUser() (users.User, error) {
 }
 
 func NewTestHost(exp *time.Time, status hosts.CertificateStatus, hostErr error) (hosts.Host, error) {
+	var reason hosts.Reason
+	if errors.Is(hostErr, hosts.ErrCertInvalid) {
+		reason = hosts.ReasonUnknownAuthority
+	}
 	str, err := RandomString(8)
 	if err != nil {
 		return hosts.Host{}, err
@@ -36,6 +41,8 @@ func NewTestHost(exp *time.Time, status hosts.CertificateStatus, hostErr error)
 	name := str + ".example.com"
 	host := hosts.Host{
 		Hostname: name,
+		Port:     hosts.DefaultPort,
+		Protocol: hosts.ProtocolTLS,
 		Certificate: hosts.CertificateInfo{
 			DNSNames:  name,
 			IP:        "",
@@ -44,9 +51,22 @@ func NewTestHost(exp *time.Time, status hosts.CertificateStatus, hostErr error)
 			Status:    status,
 			Latency:   1,
 			Signature: str,
+			Reason:    reason,
 			Error:     hostErr,
 		},
 	}
+	if exp != nil {
+		host.Certificate.Chain = []hosts.Certificate{
+			{
+				Subject:     name,
+				Issuer:      "Example Certs",
+				NotBefore:   exp.AddDate(0, -3, 0),
+				NotAfter:    *exp,
+				Fingerprint: str,
+				KeyType:     "ECDSA P-256",
+			},
+		}
+	}
 	return host, nil
 }
 
@@ -0,0 +1,40 @@
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// EmailTokenTTL is how long an email verification link is valid.
+const EmailTokenTTL = 24 * time.Hour
+
+// ParseEmail returns the normalized address of input, or an error if it isn't
+// a single plain email address.
+func ParseEmail(input string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(input))
+	if err != nil || addr.Name != "" || addr.Address != strings.TrimSpace(input) {
+		return "", fmt.Errorf("invalid email address")
+	}
+	return addr.Address, nil
+}
+
+// NewEmailToken generates an email verification token, returning the raw
+// token sent to the user and the hash that is stored.
+func NewEmailToken() (raw, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = hex.EncodeToString(b)
+	return raw, hashEmailToken(raw), nil
+}
+
+func hashEmailToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,6 @@
 package users
 
-import "github.com/lionpuro/neverexpire/notifications"
+import "time"
 
 type User struct {
 	ID    string `db:"id"`
@@ -8,13 +8,39 @@ type User struct {
 }
 
 type Settings struct {
-	WebhookURL        string
-	WebhookProvider   *notifications.WebhookProvider
-	ReminderThreshold int
+	// ReminderThresholds are the seconds before expiry at which reminders
+	// are sent, largest first.
+	ReminderThresholds []int
+	FailureThreshold   int
+	// Email receives notifications once it has been verified.
+	Email           string
+	EmailVerifiedAt *time.Time
+	// TimeZone is the IANA name of the time zone dates are shown in.
+	TimeZone string
+}
+
+func (s Settings) EmailVerified() bool {
+	return s.Email != "" && s.EmailVerifiedAt != nil
+}
+
+// Location returns the time zone of the user, or UTC if it can't be loaded.
+func (s Settings) Location() *time.Location {
+	return LoadLocation(s.TimeZone)
+}
+
+// LoadLocation returns the time zone with the IANA name, or UTC if it can't
+// be loaded.
+func LoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 type SettingsInput struct {
-	WebhookURL        *string
-	WebhookProvider   *notifications.WebhookProvider
-	ReminderThreshold *int
+	// ReminderThresholds replaces the thresholds unless nil. An empty,
+	// non-nil slice turns reminders off.
+	ReminderThresholds []int
+	FailureThreshold   *int
 }
@@ -2,6 +2,7 @@ package users
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/lionpuro/neverexpire/db"
@@ -44,13 +45,25 @@ func (r *Repository) Delete(ctx context.Context, id string) error {
 }
 
 func (r *Repository) Settings(ctx context.Context, userID string) (Settings, error) {
-	q := `SELECT webhook_provider, webhook_url, reminder_threshold FROM settings WHERE user_id = $1`
+	q := `
+	SELECT
+		reminder_thresholds,
+		failure_threshold,
+		COALESCE(email, ''),
+		email_verified_at,
+		time_zone
+	FROM settings
+	WHERE user_id = $1`
 	row := r.db.QueryRow(ctx, q, userID)
 	var vals Settings
-	if err := row.Scan(&vals.WebhookProvider, &vals.WebhookURL, &vals.ReminderThreshold); err != nil {
-		if db.IsErrNoRows(err) {
-			return Settings{}, nil
-		}
+	err := row.Scan(
+		&vals.ReminderThresholds,
+		&vals.FailureThreshold,
+		&vals.Email,
+		&vals.EmailVerifiedAt,
+		&vals.TimeZone,
+	)
+	if err != nil {
 		return Settings{}, err
 	}
 	return vals, nil
@@ -58,23 +71,89 @@ func (r *Repository) Settings(ctx context.Context, userID string) (Settings, err
 
 func (r *Repository) SaveSettings(ctx context.Context, userID string, settings SettingsInput) (Settings, error) {
 	q := `
-	INSERT INTO settings (user_id, webhook_provider, webhook_url, reminder_threshold)
+	INSERT INTO settings (user_id, reminder_thresholds, failure_threshold)
 	VALUES (
 		$1,
-		$2,
-		COALESCE($3, ''),
-		COALESCE($4, 0)
+		COALESCE($2, '{}'),
+		COALESCE($3, 2)
 	)
 	ON CONFLICT (user_id) DO UPDATE
 	SET
-		webhook_provider   = $2,
-		webhook_url        = COALESCE($3, settings.webhook_url),
-		reminder_threshold = COALESCE($4, settings.reminder_threshold)
-	RETURNING webhook_provider, webhook_url, reminder_threshold`
+		reminder_thresholds = COALESCE($2, settings.reminder_thresholds),
+		failure_threshold   = COALESCE($3, settings.failure_threshold)
+	RETURNING
+		reminder_thresholds,
+		failure_threshold,
+		COALESCE(email, ''),
+		email_verified_at,
+		time_zone`
 	var s Settings
-	row := r.db.QueryRow(ctx, q, userID, settings.WebhookProvider, settings.WebhookURL, settings.ReminderThreshold)
-	if err := row.Scan(&s.WebhookProvider, &s.WebhookURL, &s.ReminderThreshold); err != nil {
+	row := r.db.QueryRow(ctx, q,
+		userID,
+		settings.ReminderThresholds,
+		settings.FailureThreshold,
+	)
+	err := row.Scan(
+		&s.ReminderThresholds,
+		&s.FailureThreshold,
+		&s.Email,
+		&s.EmailVerifiedAt,
+		&s.TimeZone,
+	)
+	if err != nil {
 		return Settings{}, err
 	}
 	return s, nil
 }
+
+// SetEmail replaces the email address of the user with an unverified one that
+// can be verified with the token matching tokenHash until expiresAt.
+func (r *Repository) SetEmail(ctx context.Context, userID, email, tokenHash string, expiresAt time.Time) error {
+	q := `
+	INSERT INTO settings (user_id, email, email_token_hash, email_token_expires_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (user_id) DO UPDATE
+	SET
+		email                  = EXCLUDED.email,
+		email_verified_at      = NULL,
+		email_token_hash       = EXCLUDED.email_token_hash,
+		email_token_expires_at = EXCLUDED.email_token_expires_at`
+	_, err := r.db.Exec(ctx, q, userID, email, tokenHash, expiresAt)
+	return err
+}
+
+// VerifyEmail marks the email address of the user as verified if tokenHash
+// matches an unexpired token. It returns pgx.ErrNoRows otherwise.
+func (r *Repository) VerifyEmail(ctx context.Context, userID, tokenHash string, now time.Time) error {
+	q := `
+	UPDATE settings
+	SET
+		email_verified_at      = $3,
+		email_token_hash       = NULL,
+		email_token_expires_at = NULL
+	WHERE user_id = $1
+		AND email IS NOT NULL
+		AND email_token_hash = $2
+		AND email_token_expires_at > $3`
+	tag, err := r.db.Exec(ctx, q, userID, tokenHash, now)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *Repository) DeleteEmail(ctx context.Context, userID string) error {
+	q := `
+	UPDATE settings
+	SET
+		email                  = NULL,
+		email_verified_at      = NULL,
+		email_token_hash       = NULL,
+		email_token_expires_at = NULL
+	WHERE user_id = $1`
+	_, err := r.db.Exec(ctx, q, userID)
+	return err
+}
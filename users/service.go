@@ -44,3 +44,31 @@ func (s *Service) SaveSettings(userID string, settings SettingsInput) (Settings,
 	defer cancel()
 	return s.repo.SaveSettings(ctx, userID, settings)
 }
+
+// SetEmail saves an unverified email address for the user and returns the
+// token that verifies it.
+func (s *Service) SetEmail(userID, email string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	raw, hash, err := NewEmailToken()
+	if err != nil {
+		return "", err
+	}
+	expires := time.Now().UTC().Add(EmailTokenTTL)
+	if err := s.repo.SetEmail(ctx, userID, email, hash, expires); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+func (s *Service) VerifyEmail(userID, token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	return s.repo.VerifyEmail(ctx, userID, hashEmailToken(token), time.Now().UTC())
+}
+
+func (s *Service) DeleteEmail(userID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	return s.repo.DeleteEmail(ctx, userID)
+}
@@ -50,13 +50,10 @@ func TestGetUser(t *testing.T) {
 }
 
 func TestSaveSettings(t *testing.T) {
-	whp := notifications.DiscordProvider
-	whurl := "webhook.example.com"
-	th := notifications.ThresholdWeek
+	ft := 3
 	_, err := service.SaveSettings(currentUser.ID, users.SettingsInput{
-		WebhookProvider:   &whp,
-		WebhookURL:        &whurl,
-		ReminderThreshold: &th,
+		ReminderThresholds: []int{notifications.ThresholdWeek, notifications.ThresholdDay},
+		FailureThreshold:   &ft,
 	})
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -70,6 +67,29 @@ func TestGetSettings(t *testing.T) {
 	}
 }
 
+func TestVerifyEmail(t *testing.T) {
+	token, err := service.SetEmail(currentUser.ID, "alerts@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.VerifyEmail(currentUser.ID, "invalid"); err == nil {
+		t.Error("expected error verifying with an invalid token")
+	}
+	if err := service.VerifyEmail(currentUser.ID, token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sett, err := service.Settings(context.Background(), currentUser.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !sett.EmailVerified() {
+		t.Error("expected email to be verified")
+	}
+	if err := service.VerifyEmail(currentUser.ID, token); err == nil {
+		t.Error("expected error reusing a token")
+	}
+}
+
 func TestDeleteUser(t *testing.T) {
 	err := service.Delete(currentUser.ID)
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/lionpuro/neverexpire/db"
 	"github.com/lionpuro/neverexpire/notifications"
 	"github.com/lionpuro/neverexpire/users"
 	"github.com/lionpuro/neverexpire/web/views"
@@ -30,7 +31,7 @@ func (h *Handler) NotificationsPage(w http.ResponseWriter, r *http.Request) {
 		}
 		notifs = unread
 	}
-	h.render(views.Notifications(w, views.LayoutData{User: &u}, tab, notifs))
+	h.render(views.Notifications(w, h.layoutData(r.Context(), u), tab, notifs))
 }
 
 func (h *Handler) NotificationsCount(w http.ResponseWriter, r *http.Request) {
@@ -79,18 +80,48 @@ func (h *Handler) ReadNotifications(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) FailedDeliveriesPage(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFromContext(r.Context())
+	deliveries, err := h.notificationService.FailedDeliveries(r.Context(), u.ID)
+	if err != nil {
+		h.log.Error("failed to retrieve failed deliveries", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("failed to load failed deliveries"))
+		return
+	}
+	h.render(views.FailedDeliveries(w, h.layoutData(r.Context(), u), deliveries))
+}
+
+func (h *Handler) ResendDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	u, _ := userFromContext(r.Context())
+	if err := h.notificationService.ResendDelivery(u.ID, id); err != nil {
+		if db.IsErrNoRows(err) {
+			h.htmxError(w, fmt.Errorf("delivery not found"))
+			return
+		}
+		h.log.Error("failed to resend delivery", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("error resending notification"))
+		return
+	}
+	w.Header().Set("HX-Location", "/notifications/failed")
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *Handler) SettingsPage(w http.ResponseWriter, r *http.Request) {
 	u, _ := userFromContext(r.Context())
 	settings, err := h.userService.Settings(r.Context(), u.ID)
-	if err != nil {
+	if err != nil && !db.IsErrNoRows(err) {
 		h.log.Error("failed to retrieve settings", "error", err.Error())
 		h.htmxError(w, fmt.Errorf("something went wrong"))
 		return
 	}
-	if settings == (users.Settings{}) {
-		sec := notifications.Threshold2Weeks
+	if db.IsErrNoRows(err) {
 		sett, err := h.userService.SaveSettings(u.ID, users.SettingsInput{
-			ReminderThreshold: &sec,
+			ReminderThresholds: []int{notifications.Threshold2Weeks},
 		})
 		if err != nil {
 			h.log.Error("failed to save settings", "error", err.Error())
@@ -99,7 +130,26 @@ func (h *Handler) SettingsPage(w http.ResponseWriter, r *http.Request) {
 		}
 		settings = sett
 	}
-	h.render(views.Settings(w, views.LayoutData{User: &u}, settings))
+	channels, err := h.notificationService.Channels(r.Context(), u.ID)
+	if err != nil {
+		h.log.Error("failed to retrieve channels", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	schedule, err := h.notificationService.Schedule(r.Context(), u.ID)
+	if err != nil {
+		h.log.Error("failed to retrieve schedule", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	tmpl, err := h.notificationService.MessageTemplate(r.Context(), u.ID)
+	if err != nil {
+		h.log.Error("failed to retrieve message template", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	ld := views.LayoutData{User: &u, TimeZone: settings.TimeZone}
+	h.render(views.Settings(w, ld, settings, channels, schedule, tmpl, h.mailer != nil))
 }
 
 func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
@@ -123,12 +173,32 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) UpdateReminders(w http.ResponseWriter, r *http.Request) {
 	u, _ := userFromContext(r.Context())
-	seconds, err := strconv.Atoi(r.FormValue("reminder_threshold"))
+	if err := r.ParseForm(); err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	thresholds, err := parseThresholds(r.Form["reminder_thresholds"])
 	if err != nil {
 		h.htmxError(w, fmt.Errorf("bad request"))
 		return
 	}
-	if _, err := h.userService.SaveSettings(u.ID, users.SettingsInput{ReminderThreshold: &seconds}); err != nil {
+	if _, err := h.userService.SaveSettings(u.ID, users.SettingsInput{ReminderThresholds: thresholds}); err != nil {
+		h.log.Error("failed to update settings", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Retarget", "#banner-container")
+	h.render(views.SuccessBanner(w, "Settings saved"))
+}
+
+func (h *Handler) UpdateAlerts(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFromContext(r.Context())
+	failures, err := strconv.Atoi(r.FormValue("failure_threshold"))
+	if err != nil || failures < 1 {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	if _, err := h.userService.SaveSettings(u.ID, users.SettingsInput{FailureThreshold: &failures}); err != nil {
 		h.log.Error("failed to update settings", "error", err.Error())
 		h.htmxError(w, fmt.Errorf("something went wrong"))
 		return
@@ -137,33 +207,139 @@ func (h *Handler) UpdateReminders(w http.ResponseWriter, r *http.Request) {
 	h.render(views.SuccessBanner(w, "Settings saved"))
 }
 
-func (h *Handler) AddWebhook(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateDigest(w http.ResponseWriter, r *http.Request) {
 	u, _ := userFromContext(r.Context())
-	provider, url, err := parseWebhook(r.FormValue("webhook_provider"), r.FormValue("webhook_url"))
+	schedule, err := parseDigestSchedule(
+		r.FormValue("digest_frequency"),
+		r.FormValue("digest_hour"),
+		r.FormValue("digest_weekday"),
+	)
 	if err != nil {
-		h.htmxError(w, fmt.Errorf("invalid webhook"))
+		h.htmxError(w, err)
 		return
 	}
-	input := users.SettingsInput{WebhookProvider: provider, WebhookURL: &url}
-	if _, err := h.userService.SaveSettings(u.ID, input); err != nil {
-		h.log.Error("failed to save settings", "error", err.Error())
+	if err := h.notificationService.SetDigestSchedule(u.ID, schedule); err != nil {
+		h.log.Error("failed to update digest schedule", "error", err.Error())
 		h.htmxError(w, fmt.Errorf("something went wrong"))
 		return
 	}
-	if err := notifications.SendTestNotification(url); err != nil {
-		h.log.Error("failed to test notification webhook", "error", err.Error())
-		h.htmxError(w, fmt.Errorf("error sending test notification"))
+	w.Header().Set("HX-Retarget", "#banner-container")
+	h.render(views.SuccessBanner(w, "Settings saved"))
+}
+
+func (h *Handler) UpdateQuietHours(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFromContext(r.Context())
+	loc, err := parseTimeZone(r.FormValue("time_zone"))
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	quiet, err := parseQuietHours(
+		r.FormValue("quiet_hours"),
+		r.FormValue("quiet_hours_start"),
+		r.FormValue("quiet_hours_end"),
+	)
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	if err := h.notificationService.SetQuietHours(u.ID, loc, quiet); err != nil {
+		h.log.Error("failed to update quiet hours", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Retarget", "#banner-container")
+	h.render(views.SuccessBanner(w, "Settings saved"))
+}
+
+func (h *Handler) UpdateMessageTemplate(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFromContext(r.Context())
+	tmpl, err := parseMessageTemplate(r.FormValue("message_template"))
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	if err := h.notificationService.SetMessageTemplate(u.ID, tmpl); err != nil {
+		h.log.Error("failed to update message template", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Retarget", "#banner-container")
+	h.render(views.SuccessBanner(w, "Settings saved"))
+}
+
+// PreviewMessageTemplate renders the message template in the form with an
+// example reminder. Channels inherit the template of the user when theirs is
+// empty.
+func (h *Handler) PreviewMessageTemplate(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFromContext(r.Context())
+	text := normalizeTemplate(r.FormValue("message_template"))
+	if text == "" && r.FormValue("inherit") == "true" {
+		tmpl, err := h.notificationService.MessageTemplate(r.Context(), u.ID)
+		if err != nil && !db.IsErrNoRows(err) {
+			h.log.Error("failed to retrieve message template", "error", err.Error())
+			h.htmxError(w, fmt.Errorf("something went wrong"))
+			return
+		}
+		text = tmpl
+	}
+	loc := time.UTC
+	if schedule, err := h.notificationService.Schedule(r.Context(), u.ID); err == nil {
+		loc = schedule.Location
+	}
+	preview, err := notifications.PreviewMessage(text, loc, h.appURL)
+	h.render(views.TemplatePreview(w, preview, err))
+}
+
+func (h *Handler) AddEmail(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFromContext(r.Context())
+	if h.mailer == nil {
+		h.htmxError(w, fmt.Errorf("email notifications aren't available"))
+		return
+	}
+	email, err := users.ParseEmail(r.FormValue("email"))
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	token, err := h.userService.SetEmail(u.ID, email)
+	if err != nil {
+		h.log.Error("failed to save email", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	if err := h.mailer.SendVerification(email, token); err != nil {
+		h.log.Error("failed to send verification email", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("error sending verification email"))
 		return
 	}
 	w.Header().Set("HX-Location", "/settings")
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFromContext(r.Context())
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.ErrorPage(w, r, "Invalid verification link", http.StatusBadRequest)
+		return
+	}
+	if err := h.userService.VerifyEmail(u.ID, token); err != nil {
+		if db.IsErrNoRows(err) {
+			h.ErrorPage(w, r, "The verification link is invalid or has expired", http.StatusBadRequest)
+			return
+		}
+		h.log.Error("failed to verify email", "error", err.Error())
+		h.ErrorPage(w, r, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+func (h *Handler) DeleteEmail(w http.ResponseWriter, r *http.Request) {
 	u, _ := userFromContext(r.Context())
-	var s string
-	if _, err := h.userService.SaveSettings(u.ID, users.SettingsInput{WebhookURL: &s}); err != nil {
-		h.log.Error("failed to save settings", "error", err.Error())
+	if err := h.userService.DeleteEmail(u.ID); err != nil {
+		h.log.Error("failed to delete email", "error", err.Error())
 		h.htmxError(w, fmt.Errorf("something went wrong"))
 		return
 	}
@@ -15,7 +15,7 @@ func (h *Handler) APIPage(w http.ResponseWriter, r *http.Request) {
 		h.log.Error("failed to load api keys", "error", err.Error())
 		return
 	}
-	h.render(views.API(w, views.LayoutData{User: &u}, keys))
+	h.render(views.API(w, h.layoutData(r.Context(), u), keys))
 }
 
 func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,295 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/lionpuro/neverexpire/db"
+	"github.com/lionpuro/neverexpire/notifications"
+	"github.com/lionpuro/neverexpire/web/views"
+)
+
+// deliveryLogSize is how many of the latest delivery attempts are shown on
+// the channel page.
+const deliveryLogSize = 50
+
+func (h *Handler) NewChannelPage(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFromContext(r.Context())
+	h.render(views.NewChannel(w, views.LayoutData{User: &u}, h.mailer != nil))
+}
+
+func (h *Handler) ChannelPage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.ErrorPage(w, r, "Bad request", http.StatusBadRequest)
+		return
+	}
+	u, _ := userFromContext(r.Context())
+	ch, err := h.notificationService.Channel(r.Context(), id, u.ID)
+	if err != nil {
+		errCode := http.StatusNotFound
+		errMsg := "Channel not found"
+		if !db.IsErrNoRows(err) {
+			errCode = http.StatusInternalServerError
+			errMsg = "Error retrieving channel"
+			h.log.Error("failed to retrieve channel", "error", err.Error())
+		}
+		h.ErrorPage(w, r, errMsg, errCode)
+		return
+	}
+	attempts, err := h.notificationService.Attempts(r.Context(), u.ID, ch.ID, deliveryLogSize)
+	if err != nil {
+		h.log.Error("failed to retrieve delivery log", "error", err.Error())
+		h.ErrorPage(w, r, "Error retrieving channel", http.StatusInternalServerError)
+		return
+	}
+	h.render(views.Channel(w, h.layoutData(r.Context(), u), ch, attempts))
+}
+
+func (h *Handler) CreateChannel(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFromContext(r.Context())
+	ch, err := parseChannel(r.FormValue("name"), r.FormValue("provider"), r.FormValue("url"))
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	ch.UserID = u.ID
+	if ch.Provider == notifications.EmailProvider {
+		sett, err := h.userService.Settings(r.Context(), u.ID)
+		if h.mailer == nil || err != nil || !sett.EmailVerified() {
+			h.htmxError(w, fmt.Errorf("verify your email address in settings first"))
+			return
+		}
+	}
+	if ch.Provider == notifications.GenericProvider {
+		ch.Secret, err = notifications.NewWebhookSecret()
+		if err != nil {
+			h.log.Error("failed to generate webhook secret", "error", err.Error())
+			h.htmxError(w, fmt.Errorf("something went wrong"))
+			return
+		}
+	}
+	if err := h.sendTestNotification(r, ch); err != nil {
+		h.log.Error("failed to test notification channel", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("error sending test notification"))
+		return
+	}
+	id, err := h.notificationService.CreateChannel(ch)
+	if err != nil {
+		h.log.Error("failed to create channel", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	if ch.Secret != "" {
+		h.renderWebhookSecret(w, ch.Secret, fmt.Sprintf("/channels/%d", id))
+		return
+	}
+	w.Header().Set("HX-Location", fmt.Sprintf("/channels/%d", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UpdateChannel(w http.ResponseWriter, r *http.Request) {
+	ch, ok := h.channelFromRequest(w, r)
+	if !ok {
+		return
+	}
+	name, err := parseChannelName(r.FormValue("name"))
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	if name != "" {
+		ch.Name = name
+	}
+	if url := r.FormValue("url"); ch.Provider != notifications.EmailProvider && url != "" && url != ch.URL {
+		_, u, err := parseWebhook(ch.Provider.String(), url)
+		if err != nil {
+			h.htmxError(w, err)
+			return
+		}
+		ch.URL = u
+	}
+	if err := h.notificationService.UpdateChannel(ch); err != nil {
+		h.log.Error("failed to update channel", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Location", fmt.Sprintf("/channels/%d", ch.ID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UpdateChannelTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	tmpl, err := parseMessageTemplate(r.FormValue("message_template"))
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	u, _ := userFromContext(r.Context())
+	if err := h.notificationService.SetChannelTemplate(u.ID, id, tmpl); err != nil {
+		if db.IsErrNoRows(err) {
+			h.htmxError(w, fmt.Errorf("channel not found"))
+			return
+		}
+		h.log.Error("failed to update channel template", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Retarget", "#banner-container")
+	h.render(views.SuccessBanner(w, "Settings saved"))
+}
+
+func (h *Handler) DeleteChannel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	u, _ := userFromContext(r.Context())
+	if err := h.notificationService.DeleteChannel(id, u.ID); err != nil {
+		h.log.Error("failed to delete channel", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("error deleting channel"))
+		return
+	}
+	w.Header().Set("HX-Location", "/settings")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) TestChannel(w http.ResponseWriter, r *http.Request) {
+	ch, ok := h.channelFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := h.sendTestNotification(r, ch); err != nil {
+		h.log.Error("failed to test notification channel", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("error sending test notification"))
+		return
+	}
+	w.Header().Set("HX-Retarget", "#banner-container")
+	h.render(views.SuccessBanner(w, "Test notification sent"))
+}
+
+func (h *Handler) RegenerateChannelSecret(w http.ResponseWriter, r *http.Request) {
+	ch, ok := h.channelFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if ch.Provider != notifications.GenericProvider {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	secret, err := notifications.NewWebhookSecret()
+	if err != nil {
+		h.log.Error("failed to generate webhook secret", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	ch.Secret = secret
+	if err := h.notificationService.UpdateChannel(ch); err != nil {
+		h.log.Error("failed to update channel", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	h.renderWebhookSecret(w, secret, fmt.Sprintf("/channels/%d", ch.ID))
+}
+
+func (h *Handler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	rule, err := parseRule(r.Form["notification_types"], r.FormValue("min_severity"))
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	rule.ChannelID = id
+	u, _ := userFromContext(r.Context())
+	if _, err := h.notificationService.CreateRule(u.ID, rule); err != nil {
+		if db.IsErrNoRows(err) {
+			h.htmxError(w, fmt.Errorf("channel not found"))
+			return
+		}
+		h.log.Error("failed to create routing rule", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Location", fmt.Sprintf("/channels/%d", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	ruleID, err := strconv.Atoi(r.PathValue("rule"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	u, _ := userFromContext(r.Context())
+	if err := h.notificationService.DeleteRule(u.ID, id, ruleID); err != nil {
+		h.log.Error("failed to delete routing rule", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Location", fmt.Sprintf("/channels/%d", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// channelFromRequest returns the channel of the user with the id in the path,
+// writing an error response if there is none.
+func (h *Handler) channelFromRequest(w http.ResponseWriter, r *http.Request) (notifications.Channel, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return notifications.Channel{}, false
+	}
+	u, _ := userFromContext(r.Context())
+	ch, err := h.notificationService.Channel(r.Context(), id, u.ID)
+	if err != nil {
+		if db.IsErrNoRows(err) {
+			h.htmxError(w, fmt.Errorf("channel not found"))
+			return notifications.Channel{}, false
+		}
+		h.log.Error("failed to retrieve channel", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return notifications.Channel{}, false
+	}
+	return ch, true
+}
+
+func (h *Handler) sendTestNotification(r *http.Request, ch notifications.Channel) error {
+	if ch.Provider != notifications.EmailProvider {
+		return notifications.SendTestNotification(ch.Provider, ch.URL, ch.Secret)
+	}
+	u, _ := userFromContext(r.Context())
+	sett, err := h.userService.Settings(r.Context(), u.ID)
+	if err != nil {
+		return err
+	}
+	if h.mailer == nil || !sett.EmailVerified() {
+		return fmt.Errorf("no verified email address")
+	}
+	return h.mailer.SendTest(sett.Email)
+}
+
+// renderWebhookSecret shows the secret in a dialog. The secret isn't displayed
+// again after the dialog is closed.
+func (h *Handler) renderWebhookSecret(w http.ResponseWriter, secret, next string) {
+	w.Header().Set("HX-Retarget", "#webhook-secret")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	h.render(views.Component(w, "webhook-secret", map[string]string{"Secret": secret, "Next": next}))
+}
@@ -14,8 +14,12 @@ type Handler struct {
 	hostService         *hosts.Service
 	keyService          *keys.Service
 	notificationService *notifications.Service
+	mailer              *notifications.Mailer
 	Authenticator       *auth.Authenticator
 	log                 logging.Logger
+	// appURL is the public URL of the app, used in previews of message
+	// templates.
+	appURL string
 }
 
 func NewHandler(
@@ -24,6 +28,8 @@ func NewHandler(
 	hs *hosts.Service,
 	ks *keys.Service,
 	ns *notifications.Service,
+	mailer *notifications.Mailer,
+	appURL string,
 	auth *auth.Authenticator,
 ) *Handler {
 	return &Handler{
@@ -31,6 +37,8 @@ func NewHandler(
 		hostService:         hs,
 		keyService:          ks,
 		notificationService: ns,
+		mailer:              mailer,
+		appURL:              appURL,
 		Authenticator:       auth,
 		log:                 logger,
 	}
@@ -1,9 +1,11 @@
 package web
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
+	"github.com/lionpuro/neverexpire/db"
 	"github.com/lionpuro/neverexpire/users"
 	"github.com/lionpuro/neverexpire/web/views"
 )
@@ -37,6 +39,21 @@ func (h *Handler) htmxError(w http.ResponseWriter, err error) {
 	h.render(views.ErrorBanner(w, err))
 }
 
+// layoutData returns the layout data of a page of the user, which shows dates
+// in the time zone of the user.
+func (h *Handler) layoutData(ctx context.Context, u users.User) views.LayoutData {
+	ld := views.LayoutData{User: &u}
+	sett, err := h.userService.Settings(ctx, u.ID)
+	if err != nil {
+		if !db.IsErrNoRows(err) {
+			h.log.Error("failed to retrieve settings", "error", err.Error())
+		}
+		return ld
+	}
+	ld.TimeZone = sett.TimeZone
+	return ld
+}
+
 func (h *Handler) ErrorPage(w http.ResponseWriter, r *http.Request, msg string, code int) {
 	var usr *users.User
 	if u, ok := userFromContext(r.Context()); ok {
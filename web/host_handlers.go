@@ -1,10 +1,12 @@
 package web
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lionpuro/neverexpire/db"
 	"github.com/lionpuro/neverexpire/hosts"
@@ -32,7 +34,13 @@ func (h *Handler) HostPage(w http.ResponseWriter, r *http.Request) {
 		h.ErrorPage(w, r, errMsg, errCode)
 		return
 	}
-	h.render(views.Host(w, views.LayoutData{User: &u}, host))
+	history, err := h.hostService.History(r.Context(), id, u.ID)
+	if err != nil {
+		h.log.Error("failed to retrieve certificate history", "error", err.Error())
+		h.ErrorPage(w, r, "Error retrieving host data", http.StatusInternalServerError)
+		return
+	}
+	h.render(views.Host(w, h.layoutData(r.Context(), u), host, history))
 }
 
 func (h *Handler) HostsPage(w http.ResponseWriter, r *http.Request) {
@@ -43,7 +51,7 @@ func (h *Handler) HostsPage(w http.ResponseWriter, r *http.Request) {
 		h.ErrorPage(w, r, "Something went wrong", http.StatusInternalServerError)
 		return
 	}
-	h.render(views.Hosts(w, views.LayoutData{User: &u}, hosts))
+	h.render(views.Hosts(w, h.layoutData(r.Context(), u), hosts))
 }
 
 func (h *Handler) NewHostsPage(w http.ResponseWriter, r *http.Request) {
@@ -76,6 +84,169 @@ func (h *Handler) DeleteHost(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/hosts", http.StatusOK)
 }
 
+func (h *Handler) CheckHost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	u, _ := userFromContext(r.Context())
+	if _, err := h.hostService.Check(u.ID, id); err != nil {
+		var rateErr *hosts.RateLimitError
+		switch {
+		case db.IsErrNoRows(err):
+			h.htmxError(w, fmt.Errorf("host not found"))
+		case errors.As(err, &rateErr):
+			h.htmxError(w, rateErr)
+		default:
+			h.log.Error("failed to check host", "error", err.Error())
+			h.htmxError(w, fmt.Errorf("something went wrong"))
+		}
+		return
+	}
+	w.Header().Set("HX-Location", fmt.Sprintf("/hosts/%d", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UpdateHostSettings(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	thresholds, err := hosts.ParseReminderDays(r.FormValue("reminder_days"))
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	tags, err := hosts.ParseTags(r.FormValue("tags"))
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	settings := hosts.HostSettings{
+		ReminderThresholds: thresholds,
+		Muted:              r.FormValue("muted") == "on",
+		Tags:               tags,
+	}
+	u, _ := userFromContext(r.Context())
+	if err := h.hostService.UpdateSettings(u.ID, id, settings); err != nil {
+		if db.IsErrNoRows(err) {
+			h.htmxError(w, fmt.Errorf("host not found"))
+			return
+		}
+		h.log.Error("failed to update host settings", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Retarget", "#banner-container")
+	h.render(views.SuccessBanner(w, "Settings saved"))
+}
+
+// hostActionLocation returns the page to show after snoozing or
+// acknowledging a host: the notifications page if the action was taken there,
+// otherwise the page of the host.
+func hostActionLocation(r *http.Request, id int) string {
+	if r.FormValue("from") == "notifications" {
+		return "/notifications"
+	}
+	return fmt.Sprintf("/hosts/%d", id)
+}
+
+func (h *Handler) SnoozeHost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	u, _ := userFromContext(r.Context())
+	loc := time.UTC
+	if sett, err := h.userService.Settings(r.Context(), u.ID); err == nil {
+		loc = sett.Location()
+	}
+	until, err := parseSnoozeDate(r.FormValue("snoozed_until"), loc, time.Now())
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	if err := h.hostService.Snooze(u.ID, id, &until); err != nil {
+		if db.IsErrNoRows(err) {
+			h.htmxError(w, fmt.Errorf("host not found"))
+			return
+		}
+		h.log.Error("failed to snooze host", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Location", hostActionLocation(r, id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UnsnoozeHost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	u, _ := userFromContext(r.Context())
+	if err := h.hostService.Snooze(u.ID, id, nil); err != nil {
+		if db.IsErrNoRows(err) {
+			h.htmxError(w, fmt.Errorf("host not found"))
+			return
+		}
+		h.log.Error("failed to end snooze", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Location", hostActionLocation(r, id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) AcknowledgeHost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	note, err := hosts.ParseAcknowledgementNote(r.FormValue("note"))
+	if err != nil {
+		h.htmxError(w, err)
+		return
+	}
+	u, _ := userFromContext(r.Context())
+	if err := h.hostService.Acknowledge(u.ID, id, note); err != nil {
+		if db.IsErrNoRows(err) {
+			h.htmxError(w, fmt.Errorf("host not found"))
+			return
+		}
+		h.log.Error("failed to acknowledge host", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Location", hostActionLocation(r, id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UnacknowledgeHost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.htmxError(w, fmt.Errorf("bad request"))
+		return
+	}
+	u, _ := userFromContext(r.Context())
+	if err := h.hostService.Unacknowledge(u.ID, id); err != nil {
+		if db.IsErrNoRows(err) {
+			h.htmxError(w, fmt.Errorf("host not found"))
+			return
+		}
+		h.log.Error("failed to remove acknowledgement", "error", err.Error())
+		h.htmxError(w, fmt.Errorf("something went wrong"))
+		return
+	}
+	w.Header().Set("HX-Location", hostActionLocation(r, id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *Handler) CreateHosts(w http.ResponseWriter, r *http.Request) {
 	u, _ := userFromContext(r.Context())
 	input := strings.TrimSpace(r.FormValue("hosts"))
@@ -84,15 +255,16 @@ func (h *Handler) CreateHosts(w http.ResponseWriter, r *http.Request) {
 		h.htmxError(w, fmt.Errorf("please enter at least one valid host"))
 		return
 	}
-	var names []string
+	protocol := r.FormValue("protocol")
+	var targets []hosts.Host
 	var errs []error
 	for _, h := range hs {
-		name, err := hosts.ParseHostname(h)
+		target, err := hosts.ParseTarget(h, protocol)
 		if err != nil {
 			errs = append(errs, err)
 		}
-		if name != "" {
-			names = append(names, name)
+		if target.Hostname != "" {
+			targets = append(targets, target)
 		}
 	}
 	if len(errs) > 0 {
@@ -105,14 +277,11 @@ func (h *Handler) CreateHosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.hostService.Create(u.ID, names); err != nil {
+	if err := h.hostService.Create(u.ID, targets); err != nil {
 		e := fmt.Errorf("error adding host")
-		switch {
-		case
-			strings.Contains(err.Error(), "already tracking"),
-			strings.Contains(err.Error(), "can't connect to"):
+		if errors.Is(err, hosts.ErrAlreadyTracking) {
 			e = err
-		default:
+		} else {
 			h.log.Error("failed to create host", "error", err.Error())
 		}
 		h.htmxError(w, e)
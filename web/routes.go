@@ -25,16 +25,40 @@ func NewRouter(h *Handler) *http.ServeMux {
 	handle("POST", "/hosts", h.RequireAuth(h.CreateHosts))
 	handle("GET", "/hosts/{id}", h.RequireAuth(h.HostPage))
 	handle("DELETE", "/hosts/{id}", h.RequireAuth(h.DeleteHost))
+	handle("POST", "/hosts/{id}/check", h.RequireAuth(h.CheckHost))
+	handle("PUT", "/hosts/{id}/settings", h.RequireAuth(h.UpdateHostSettings))
+	handle("PUT", "/hosts/{id}/snooze", h.RequireAuth(h.SnoozeHost))
+	handle("DELETE", "/hosts/{id}/snooze", h.RequireAuth(h.UnsnoozeHost))
+	handle("POST", "/hosts/{id}/acknowledge", h.RequireAuth(h.AcknowledgeHost))
+	handle("DELETE", "/hosts/{id}/acknowledge", h.RequireAuth(h.UnacknowledgeHost))
 	handle("GET", "/notifications", h.RequireAuth(h.NotificationsPage))
 	handle("GET", "/partials/notifications/count", h.RequireAuth(h.NotificationsCount))
 	handle("PATCH", "/notifications/read", h.RequireAuth(h.ReadNotifications))
+	handle("GET", "/notifications/failed", h.RequireAuth(h.FailedDeliveriesPage))
+	handle("POST", "/notifications/failed/{id}/resend", h.RequireAuth(h.ResendDelivery))
 	handle("GET", "/login", h.LoginPage)
 	handle("GET", "/logout", h.Logout)
 	handle("DELETE", "/account", h.RequireAuth(h.DeleteAccount))
 	handle("GET", "/settings", h.RequireAuth(h.SettingsPage))
 	handle("PUT", "/settings/reminders", h.RequireAuth(h.UpdateReminders))
-	handle("POST", "/settings/webhook", h.RequireAuth(h.AddWebhook))
-	handle("DELETE", "/settings/webhook", h.RequireAuth(h.DeleteWebhook))
+	handle("PUT", "/settings/alerts", h.RequireAuth(h.UpdateAlerts))
+	handle("PUT", "/settings/digest", h.RequireAuth(h.UpdateDigest))
+	handle("PUT", "/settings/quiet-hours", h.RequireAuth(h.UpdateQuietHours))
+	handle("PUT", "/settings/template", h.RequireAuth(h.UpdateMessageTemplate))
+	handle("POST", "/settings/template/preview", h.RequireAuth(h.PreviewMessageTemplate))
+	handle("POST", "/settings/email", h.RequireAuth(h.AddEmail))
+	handle("GET", "/settings/email/verify", h.RequireAuth(h.VerifyEmail))
+	handle("DELETE", "/settings/email", h.RequireAuth(h.DeleteEmail))
+	handle("GET", "/channels/new", h.RequireAuth(h.NewChannelPage))
+	handle("POST", "/channels", h.RequireAuth(h.CreateChannel))
+	handle("GET", "/channels/{id}", h.RequireAuth(h.ChannelPage))
+	handle("PUT", "/channels/{id}", h.RequireAuth(h.UpdateChannel))
+	handle("DELETE", "/channels/{id}", h.RequireAuth(h.DeleteChannel))
+	handle("PUT", "/channels/{id}/template", h.RequireAuth(h.UpdateChannelTemplate))
+	handle("POST", "/channels/{id}/test", h.RequireAuth(h.TestChannel))
+	handle("POST", "/channels/{id}/secret", h.RequireAuth(h.RegenerateChannelSecret))
+	handle("POST", "/channels/{id}/rules", h.RequireAuth(h.CreateRule))
+	handle("DELETE", "/channels/{id}/rules/{rule}", h.RequireAuth(h.DeleteRule))
 	handle("GET", "/account/api", h.RequireAuth(h.APIPage))
 	handle("GET", "/account/tokens/new", h.RequireAuth(h.CreateAPIKey))
 	handle("DELETE", "/account/tokens/{id}", h.RequireAuth(h.DeleteAPIKey))
@@ -2,8 +2,13 @@ package web
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/lionpuro/neverexpire/hosts"
 	"github.com/lionpuro/neverexpire/notifications"
 )
 
@@ -21,3 +26,160 @@ func parseWebhook(provider, url string) (*notifications.WebhookProvider, string,
 	}
 	return &p, u, nil
 }
+
+// maxChannelName is the maximum length of a channel name.
+const maxChannelName = 64
+
+// parseChannel validates the input of a new channel. Email channels don't
+// have a URL. The name defaults to the label of the provider.
+func parseChannel(name, provider, url string) (notifications.Channel, error) {
+	var ch notifications.Channel
+	if provider == string(notifications.EmailProvider) {
+		ch.Provider = notifications.EmailProvider
+	} else {
+		p, u, err := parseWebhook(provider, url)
+		if err != nil {
+			return notifications.Channel{}, err
+		}
+		ch.Provider, ch.URL = *p, u
+	}
+	n, err := parseChannelName(name)
+	if err != nil {
+		return notifications.Channel{}, err
+	}
+	if n == "" {
+		n = ch.Provider.Label()
+	}
+	ch.Name = n
+	return ch, nil
+}
+
+func parseChannelName(name string) (string, error) {
+	n := strings.TrimSpace(name)
+	if utf8.RuneCountInString(n) > maxChannelName {
+		return "", fmt.Errorf("channel name is too long")
+	}
+	return n, nil
+}
+
+// parseRule parses the notification types and the minimum severity of a
+// routing rule. No types matches every type.
+func parseRule(types []string, severity string) (notifications.Rule, error) {
+	var rule notifications.Rule
+	for _, v := range types {
+		n, err := strconv.Atoi(v)
+		if err != nil || !slices.Contains(notifications.NotificationTypes, notifications.NotificationType(n)) {
+			return notifications.Rule{}, fmt.Errorf("invalid notification type: %s", v)
+		}
+		if t := notifications.NotificationType(n); !slices.Contains(rule.Types, t) {
+			rule.Types = append(rule.Types, t)
+		}
+	}
+	n, err := strconv.Atoi(severity)
+	if err != nil || !slices.Contains(notifications.Severities, notifications.Severity(n)) {
+		return notifications.Rule{}, fmt.Errorf("invalid severity: %s", severity)
+	}
+	rule.MinSeverity = notifications.Severity(n)
+	return rule, nil
+}
+
+// parseDigestSchedule parses the frequency, the hour and the day of the week
+// of a digest schedule.
+func parseDigestSchedule(frequency, hour, weekday string) (notifications.DigestSchedule, error) {
+	f, err := strconv.Atoi(frequency)
+	if err != nil || !slices.Contains(notifications.DigestFrequencies, notifications.DigestFrequency(f)) {
+		return notifications.DigestSchedule{}, fmt.Errorf("invalid digest frequency")
+	}
+	h, err := strconv.Atoi(hour)
+	if err != nil || h < 0 || h > 23 {
+		return notifications.DigestSchedule{}, fmt.Errorf("invalid digest hour")
+	}
+	d, err := strconv.Atoi(weekday)
+	if err != nil || d < int(time.Sunday) || d > int(time.Saturday) {
+		return notifications.DigestSchedule{}, fmt.Errorf("invalid digest weekday")
+	}
+	return notifications.DigestSchedule{
+		Frequency: notifications.DigestFrequency(f),
+		Hour:      h,
+		Weekday:   time.Weekday(d),
+	}, nil
+}
+
+// parseTimeZone parses the IANA name of a time zone.
+func parseTimeZone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("invalid time zone")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// parseQuietHours parses the start and end hours of quiet hours, which are
+// off unless enabled is "on".
+func parseQuietHours(enabled, start, end string) (notifications.QuietHours, error) {
+	if enabled != "on" {
+		return notifications.DefaultQuietHours, nil
+	}
+	s, err := strconv.Atoi(start)
+	if err != nil || s < 0 || s > 23 {
+		return notifications.QuietHours{}, fmt.Errorf("invalid start of quiet hours")
+	}
+	e, err := strconv.Atoi(end)
+	if err != nil || e < 0 || e > 23 {
+		return notifications.QuietHours{}, fmt.Errorf("invalid end of quiet hours")
+	}
+	if s == e {
+		return notifications.QuietHours{}, fmt.Errorf("quiet hours must start and end at different times")
+	}
+	return notifications.QuietHours{Enabled: true, Start: s, End: e}, nil
+}
+
+// normalizeTemplate converts the line endings of a message template from a
+// form and trims the surrounding space.
+func normalizeTemplate(text string) string {
+	return strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+}
+
+// parseMessageTemplate normalizes a message template and checks that it's
+// valid. An empty template is valid and restores the default messages.
+func parseMessageTemplate(text string) (string, error) {
+	text = normalizeTemplate(text)
+	if text == "" {
+		return "", nil
+	}
+	if _, err := notifications.ParseMessageTemplate(text); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+// parseThresholds parses the selected reminder thresholds, returning them
+// largest first. No selection returns an empty, non-nil slice.
+func parseThresholds(values []string) ([]int, error) {
+	thresholds := make([]int, len(values))
+	for i, v := range values {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reminder threshold: %s", v)
+		}
+		thresholds[i] = seconds
+	}
+	return notifications.CheckThresholds(thresholds)
+}
+
+// parseSnoozeDate parses the date a snooze ends on. The snooze ends at the
+// start of the date in loc.
+func parseSnoozeDate(value string, loc *time.Location, now time.Time) (time.Time, error) {
+	date, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(value), loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date")
+	}
+	if err := hosts.CheckSnooze(date, now); err != nil {
+		return time.Time{}, err
+	}
+	return date.UTC(), nil
+}
@@ -6,14 +6,13 @@ import (
 	"html/template"
 	"io"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/lionpuro/neverexpire/hosts"
 	"github.com/lionpuro/neverexpire/keys"
 	"github.com/lionpuro/neverexpire/notifications"
 	"github.com/lionpuro/neverexpire/users"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
 //go:embed templates
@@ -26,6 +25,9 @@ type viewTemplate struct {
 type LayoutData struct {
 	User  *users.User
 	Error error
+	// TimeZone is the IANA name of the time zone dates are shown in. Dates
+	// are shown in the time zone of the browser if it's empty.
+	TimeZone string
 }
 
 type Config struct {
@@ -43,9 +45,12 @@ var (
 	hostTmpl          = parse("pages/hosts/host.html")
 	newHostsTmpl      = parse("pages/hosts/new.html")
 	settingsTmpl      = parse("pages/settings.html")
+	newChannelTmpl    = parse("pages/channels/new.html")
+	channelTmpl       = parse("pages/channels/channel.html")
 	apiTmpl           = parse("pages/api.html")
 	loginTmpl         = parse("pages/login.html")
 	notificationsTmpl = parse("pages/notifications.html")
+	deliveriesTmpl    = parse("pages/deliveries.html")
 	privacyTmpl       = parse("pages/privacy.html")
 	partials          = parsePartials()
 )
@@ -76,19 +81,34 @@ func Hosts(w io.Writer, ld LayoutData, hosts []hosts.Host) error {
 	return hostsTmpl.render(w, data)
 }
 
-func Host(w io.Writer, ld LayoutData, h hosts.Host) error {
+func Host(w io.Writer, ld LayoutData, h hosts.Host, history []hosts.HistoryEntry) error {
+	minDate, maxDate := snoozeDates(ld)
 	return hostTmpl.render(w, map[string]any{
-		"Config":     defaultConfig(),
-		"LayoutData": ld,
-		"Host":       h,
+		"Config":       defaultConfig(),
+		"LayoutData":   ld,
+		"Host":         h,
+		"History":      history,
+		"Snoozed":      h.Snoozed(time.Now()),
+		"Acknowledged": h.Acknowledged(),
+		"SnoozeMin":    minDate,
+		"SnoozeMax":    maxDate,
 	})
 }
 
+// snoozeDates returns the first and the last date hosts can be snoozed until,
+// in the time zone of the user.
+func snoozeDates(ld LayoutData) (string, string) {
+	now := time.Now().In(users.LoadLocation(ld.TimeZone))
+	return now.AddDate(0, 0, 1).Format("2006-01-02"),
+		now.AddDate(0, 0, hosts.MaxSnoozeDays).Format("2006-01-02")
+}
+
 func NewHosts(w io.Writer, ld LayoutData, inputValue string) error {
 	data := map[string]any{
 		"Config":     defaultConfig(),
 		"LayoutData": ld,
 		"InputValue": inputValue,
+		"Protocols":  hosts.Protocols,
 	}
 	if inputValue == "" {
 		data["InputValue"] = nil
@@ -96,43 +116,107 @@ func NewHosts(w io.Writer, ld LayoutData, inputValue string) error {
 	return newHostsTmpl.render(w, data)
 }
 
-func Settings(w io.Writer, ld LayoutData, sett users.Settings) error {
-	title := func(s string) string {
-		return cases.Title(language.English, cases.Compact).String(s)
-	}
-	whOpts := []struct {
-		Label string
-		Value notifications.WebhookProvider
-	}{
-		{
-			Label: title(string(notifications.DiscordProvider)),
-			Value: notifications.DiscordProvider,
-		},
-		{
-			Label: title(string(notifications.SlackProvider)),
-			Value: notifications.SlackProvider,
-		},
-	}
+// Settings renders the settings page. emailEnabled is false when email
+// notifications haven't been configured.
+func Settings(
+	w io.Writer,
+	ld LayoutData,
+	sett users.Settings,
+	channels []notifications.Channel,
+	schedule notifications.Schedule,
+	messageTemplate string,
+	emailEnabled bool,
+) error {
 	type reminder struct {
-		Value   int
-		Display string
+		Value    int
+		Display  string
+		Selected bool
 	}
 	opts := []reminder{
-		{Value: notifications.ThresholdDay, Display: "1 day before"},
-		{Value: notifications.Threshold2Days, Display: "2 days before"},
-		{Value: notifications.ThresholdWeek, Display: "1 week before"},
+		{Value: notifications.ThresholdMonth, Display: "30 days before"},
 		{Value: notifications.Threshold2Weeks, Display: "2 weeks before"},
+		{Value: notifications.ThresholdWeek, Display: "1 week before"},
+		{Value: notifications.Threshold2Days, Display: "2 days before"},
+		{Value: notifications.ThresholdDay, Display: "1 day before"},
+		{Value: notifications.ThresholdExpired, Display: "When expired"},
+	}
+	for i, opt := range opts {
+		opts[i].Selected = slices.Contains(sett.ReminderThresholds, opt.Value)
+	}
+	failureOpts := []reminder{
+		{Value: 1, Display: "After 1 failed check"},
+		{Value: 2, Display: "After 2 failed checks in a row"},
+		{Value: 3, Display: "After 3 failed checks in a row"},
+		{Value: 5, Display: "After 5 failed checks in a row"},
 	}
 	data := map[string]any{
-		"Config":          defaultConfig(),
-		"LayoutData":      ld,
-		"ReminderOptions": opts,
-		"Settings":        sett,
-		"WebhookOptions":  whOpts,
+		"Config":            defaultConfig(),
+		"EmailEnabled":      emailEnabled,
+		"LayoutData":        ld,
+		"FailureOptions":    failureOpts,
+		"ReminderOptions":   opts,
+		"Settings":          sett,
+		"Channels":          channels,
+		"TimeZone":          schedule.Location.String(),
+		"QuietHours":        schedule.QuietHours,
+		"Digest":            schedule.Digest,
+		"DigestFrequencies": notifications.DigestFrequencies,
+		"Weekdays":          weekdays,
+		"Hours":             hours,
+		"MessageTemplate":   messageTemplate,
+		"MaxTemplateLength": notifications.MaxTemplateLength,
 	}
 	return settingsTmpl.render(w, data)
 }
 
+var weekdays = []time.Weekday{
+	time.Monday,
+	time.Tuesday,
+	time.Wednesday,
+	time.Thursday,
+	time.Friday,
+	time.Saturday,
+	time.Sunday,
+}
+
+var hours = func() []int {
+	h := make([]int, 24)
+	for i := range h {
+		h[i] = i
+	}
+	return h
+}()
+
+// NewChannel renders the page for adding a channel. emailEnabled is false when
+// email notifications haven't been configured.
+func NewChannel(w io.Writer, ld LayoutData, emailEnabled bool) error {
+	var providers []notifications.WebhookProvider
+	for _, p := range notifications.ChannelProviders {
+		if p != notifications.EmailProvider || emailEnabled {
+			providers = append(providers, p)
+		}
+	}
+	return newChannelTmpl.render(w, map[string]any{
+		"Config":     defaultConfig(),
+		"LayoutData": ld,
+		"Providers":  providers,
+	})
+}
+
+// Channel renders the settings of the channel and its latest delivery
+// attempts.
+func Channel(w io.Writer, ld LayoutData, ch notifications.Channel, attempts []notifications.Attempt) error {
+	return channelTmpl.render(w, map[string]any{
+		"Config":            defaultConfig(),
+		"LayoutData":        ld,
+		"Channel":           ch,
+		"Attempts":          attempts,
+		"NotificationTypes": notifications.NotificationTypes,
+		"Severities":        notifications.Severities,
+		"MaxTemplateLength": notifications.MaxTemplateLength,
+	})
+}
+
 func API(w io.Writer, ld LayoutData, keys []keys.AccessKey) error {
 	return apiTmpl.render(w, map[string]any{
 		"Config":     defaultConfig(),
@@ -146,15 +230,28 @@ func Login(w io.Writer) error {
 }
 
 func Notifications(w io.Writer, ld LayoutData, tab string, notifs []notifications.AppNotification) error {
+	minDate, maxDate := snoozeDates(ld)
 	data := map[string]any{
 		"Config":        defaultConfig(),
 		"LayoutData":    ld,
 		"Tab":           tab,
 		"Notifications": notifs,
+		"SnoozeMin":     minDate,
+		"SnoozeMax":     maxDate,
 	}
 	return notificationsTmpl.render(w, data)
 }
 
+// FailedDeliveries renders the deliveries that were given up after running
+// out of attempts.
+func FailedDeliveries(w io.Writer, ld LayoutData, deliveries []notifications.OutboxEntry) error {
+	return deliveriesTmpl.render(w, map[string]any{
+		"Config":     defaultConfig(),
+		"LayoutData": ld,
+		"Deliveries": deliveries,
+	})
+}
+
 func Privacy(w io.Writer, ld LayoutData) error {
 	return privacyTmpl.render(w, map[string]any{
 		"Config":     defaultConfig(),
@@ -169,6 +266,15 @@ func SuccessBanner(w io.Writer, msg string) error {
 	return partials.renderPartial(w, "success-banner", map[string]any{"Message": msg})
 }
 
+// TemplatePreview renders the preview of a message template, or the error
+// that makes it invalid.
+func TemplatePreview(w io.Writer, preview string, err error) error {
+	return partials.renderPartial(w, "template-preview", map[string]any{
+		"Preview": preview,
+		"Error":   err,
+	})
+}
+
 func Component(w io.Writer, name string, data any) error {
 	return partials.renderPartial(w, name, data)
 }
@@ -2,7 +2,9 @@ package views_test
 
 import (
 	"bytes"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lionpuro/neverexpire/hosts"
 	"github.com/lionpuro/neverexpire/keys"
@@ -15,12 +17,73 @@ func TestRender(t *testing.T) {
 	testUser := &users.User{
 		Email: "tester@neverexpire.lionpuro.com",
 	}
+	testChannel := notifications.Channel{
+		ID:       1,
+		Name:     "On-call",
+		Provider: notifications.PagerDutyProvider,
+		URL:      "R0UTINGKEY0000000000000000000000",
+		Rules: []notifications.Rule{
+			{ID: 1, ChannelID: 1},
+			{
+				ID:          2,
+				ChannelID:   1,
+				Types:       []notifications.NotificationType{notifications.NotificationTypeOffline, notifications.NotificationTypeInvalid},
+				MinSeverity: notifications.SeverityCritical,
+			},
+		},
+	}
 	testHosts := []hosts.Host{
 		{
 			ID:          1,
 			Hostname:    "neverexpire.lionpuro.com",
 			Certificate: hosts.CertificateInfo{},
 		},
+		{
+			ID:       2,
+			Hostname: "mail.lionpuro.com",
+			Port:     587,
+			Protocol: hosts.ProtocolSMTP,
+			Certificate: hosts.CertificateInfo{
+				Chain: []hosts.Certificate{
+					{Subject: "mail.lionpuro.com", Issuer: "R11"},
+					{Subject: "R11", Issuer: "ISRG Root X1"},
+				},
+			},
+		},
+		{
+			ID:       3,
+			Hostname: "www.lionpuro.com",
+			Settings: hosts.HostSettings{
+				ReminderThresholds: []int{60 * 24 * 60 * 60, 7 * 24 * 60 * 60},
+				Muted:              true,
+				Tags:               []string{"production", "team:web"},
+			},
+			Certificate: hosts.CertificateInfo{
+				Status:              hosts.CertificateStatusHealthy,
+				FingerprintMismatch: true,
+				Endpoints: []hosts.Endpoint{
+					{IP: "192.0.2.1:443", Status: hosts.CertificateStatusHealthy, Fingerprint: "ab12"},
+					{IP: "192.0.2.2:443", Status: hosts.CertificateStatusHealthy, Fingerprint: "cd34"},
+					{IP: "[2001:db8::1]:443", Status: hosts.CertificateStatusOffline, Error: "connection refused"},
+				},
+			},
+		},
+	}
+	testHistory := []hosts.HistoryEntry{
+		{
+			Fingerprint: "cd34",
+			Subject:     "neverexpire.lionpuro.com",
+			Issuer:      "Let's Encrypt",
+			FirstSeen:   time.Now().UTC().AddDate(0, 0, -10),
+			LastSeen:    time.Now().UTC(),
+		},
+		{
+			Fingerprint: "ab12",
+			Subject:     "neverexpire.lionpuro.com",
+			Issuer:      "Let's Encrypt",
+			FirstSeen:   time.Now().UTC().AddDate(0, 0, -70),
+			LastSeen:    time.Now().UTC().AddDate(0, 0, -10),
+		},
 	}
 	// Home
 	t.Run("home (logged out)", func(t *testing.T) {
@@ -48,10 +111,31 @@ func TestRender(t *testing.T) {
 	// Host
 	t.Run("host", func(t *testing.T) {
 		buf := bytes.Buffer{}
-		err := views.Host(&buf, views.LayoutData{User: testUser}, testHosts[0])
-		if err != nil {
+		for _, h := range testHosts {
+			err := views.Host(&buf, views.LayoutData{User: testUser}, h, testHistory)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+	t.Run("host (snoozed and acknowledged)", func(t *testing.T) {
+		until := time.Now().UTC().AddDate(0, 0, 7)
+		acked := time.Now().UTC()
+		h := testHosts[0]
+		h.Settings.SnoozedUntil = &until
+		h.Settings.AcknowledgedAt = &acked
+		h.Settings.AcknowledgementNote = "renewal ticket filed"
+		var buf bytes.Buffer
+		if err := views.Host(&buf, views.LayoutData{User: testUser}, h, nil); err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
+		out := buf.String()
+		if !strings.Contains(out, "End snooze") || !strings.Contains(out, "renewal ticket filed") {
+			t.Errorf("expected the snooze and the acknowledgement to be shown")
+		}
+		if strings.Contains(out, `name="snoozed_until"`) {
+			t.Errorf("expected no snooze form on a snoozed host")
+		}
 	})
 	// NewHost
 	t.Run("new host", func(t *testing.T) {
@@ -64,14 +148,74 @@ func TestRender(t *testing.T) {
 	// Settings
 	t.Run("settings", func(t *testing.T) {
 		buf := bytes.Buffer{}
-		err := views.Settings(
+		loc, err := time.LoadLocation("Europe/Helsinki")
+		if err != nil {
+			t.Fatalf("failed to load location: %v", err)
+		}
+		err = views.Settings(
 			&buf,
-			views.LayoutData{User: testUser},
-			users.Settings{},
+			views.LayoutData{User: testUser, TimeZone: loc.String()},
+			users.Settings{TimeZone: loc.String()},
+			[]notifications.Channel{testChannel},
+			notifications.Schedule{
+				Location:   loc,
+				QuietHours: notifications.QuietHours{Enabled: true, Start: 22, End: 7},
+				Digest:     notifications.DigestSchedule{Frequency: notifications.DigestWeekly, Hour: 8, Weekday: time.Friday},
+			},
+			"{{.Hostname}} expires in {{.DaysLeft}} days",
+			true,
 		)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
+		if !strings.Contains(buf.String(), `<meta name="time-zone" content="Europe/Helsinki" />`) {
+			t.Error("expected the time zone of the user in the layout")
+		}
+		if !strings.Contains(buf.String(), `value="Europe/Helsinki"`) {
+			t.Error("expected the time zone in the settings form")
+		}
+		if !strings.Contains(buf.String(), ">{{.Hostname}} expires in {{.DaysLeft}} days</textarea>") {
+			t.Error("expected the message template in the settings form")
+		}
+	})
+	// Channels
+	t.Run("new channel", func(t *testing.T) {
+		err := views.NewChannel(&bytes.Buffer{}, views.LayoutData{User: testUser}, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run("channel", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		failing := testChannel
+		failing.ConsecutiveFailures = notifications.ChannelFailingThreshold
+		failing.MessageTemplate = "{{.Message}}"
+		attempts := []notifications.Attempt{
+			{ID: 2, StatusCode: 500, Latency: 120 * time.Millisecond, Error: "response status: 500 Internal Server Error", CreatedAt: time.Now()},
+			{ID: 1, StatusCode: 202, Response: `{"status":"success"}`, Latency: 80 * time.Millisecond, CreatedAt: time.Now()},
+		}
+		err := views.Channel(&buf, views.LayoutData{User: testUser}, failing, attempts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "Host offline, Invalid certificate") {
+			t.Error("expected rule types to be listed")
+		}
+		if !strings.Contains(buf.String(), "HTTP 500") {
+			t.Error("expected the delivery log to be listed")
+		}
+		if !strings.Contains(buf.String(), `hx-put="/channels/1/template"`) || !strings.Contains(buf.String(), ">{{.Message}}</textarea>") {
+			t.Error("expected the message template form of the channel")
+		}
+	})
+	t.Run("template preview", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		if err := views.TemplatePreview(&buf, "example.com expires in 14 days", nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), ">example.com expires in 14 days</pre>") {
+			t.Errorf("expected the preview, got %q", buf.String())
+		}
 	})
 	// API
 	t.Run("api", func(t *testing.T) {
@@ -96,6 +240,51 @@ func TestRender(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 	})
+	t.Run("notifications (acknowledged)", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := views.Notifications(
+			&buf,
+			views.LayoutData{User: testUser},
+			"",
+			[]notifications.AppNotification{
+				{ID: 1, HostID: 1, Body: "example.com is unreachable (3 checks in a row)", Acknowledged: true},
+				{ID: 2, HostID: 2, Body: "TLS certificate for example.org will expire in 7 days"},
+			},
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out := buf.String()
+		if strings.Count(out, `hx-post="/hosts/2/acknowledge"`) != 1 || strings.Contains(out, `hx-post="/hosts/1/acknowledge"`) {
+			t.Errorf("expected actions only for the notification that isn't acknowledged")
+		}
+	})
+	t.Run("failed deliveries", func(t *testing.T) {
+		failedAt := time.Now().UTC()
+		var buf bytes.Buffer
+		err := views.FailedDeliveries(
+			&buf,
+			views.LayoutData{User: testUser},
+			[]notifications.OutboxEntry{
+				{
+					Delivery: notifications.Delivery{
+						ID:       3,
+						Attempts: notifications.MaxDeliveryAttempts,
+						FailedAt: &failedAt,
+						Error:    "response status: 500 Internal Server Error",
+					},
+					Notification: notifications.Notification{Body: "TLS certificate for example.com will expire in 2 days"},
+					Channel:      testChannel,
+				},
+			},
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "/notifications/failed/3/resend") {
+			t.Errorf("expected a resend action for the delivery")
+		}
+	})
 	// Privacy
 	t.Run("privacy", func(t *testing.T) {
 		err := views.Privacy(